@@ -524,7 +524,10 @@ func TestDebugExampleE2E(t *testing.T) {
 	// ── 10. Generate PDF ───────────────────────────────────────────────────────
 	t.Log("10. generate PDF")
 	// Let report-build derive the stamped filename from dbgDir automatically.
+	// The report above is hand-written rather than built with report-*
+	// commands, so skip report-check.
 	run(t, exampleDir, "delve-helper", "report-build",
+		"-force",
 		"-pkg", "example",
 		"-date", date,
 		"-pdf",
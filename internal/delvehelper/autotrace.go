@@ -0,0 +1,139 @@
+// Automatic Debugging Trace rows: with DLV_AUTO_TRACE=1 and DBG_DIR set,
+// break, clear, continue, next, step, stepout and restart append their own
+// numbered rows to 10_trace.md. The agent supplies the reasoning with
+// -reason on the command or later with report-annotate <n>.
+package delvehelper
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-delve/delve/service/api"
+)
+
+// autoTraceCommands are the session commands that append trace rows and
+// therefore accept -reason.
+var autoTraceCommands = map[string]bool{
+	"break": true, "clear": true, "continue": true, "next": true,
+	"step": true, "stepout": true, "restart": true,
+	"c": true, "n": true, "s": true, "so": true,
+}
+
+// traceReason is the -reason of the command being executed; the daemon runs
+// one request at a time, so a package variable is enough.
+var traceReason string
+
+// autoTraceDir returns the artifact dir to append rows to, or "" when the
+// mode is off.
+func autoTraceDir() string {
+	v := strings.TrimSpace(os.Getenv("DLV_AUTO_TRACE"))
+	if v == "" || v == "0" {
+		return ""
+	}
+	return os.Getenv("DBG_DIR")
+}
+
+// takeReason returns the -reason of an auto-trace command and its args
+// without it. While the mode is off, args pass through unchanged: there is
+// no row for a reason to go into, and -reason may be the command's own.
+func takeReason(cmd string, args []string) (string, []string) {
+	if !autoTraceCommands[cmd] || autoTraceDir() == "" {
+		return "", args
+	}
+	return extractReason(args)
+}
+
+// extractReason removes -reason TEXT (or -reason=TEXT) from args.
+func extractReason(args []string) (string, []string) {
+	var reason string
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case (a == "-reason" || a == "--reason") && i+1 < len(args):
+			reason = args[i+1]
+			i++
+		case strings.HasPrefix(a, "-reason=") || strings.HasPrefix(a, "--reason="):
+			_, reason, _ = strings.Cut(a, "=")
+		default:
+			rest = append(rest, a)
+		}
+	}
+	return reason, rest
+}
+
+// autoTrace appends one row when the mode is on and reports it on stdout so
+// the agent knows which number to annotate.
+func autoTrace(action, loc string) {
+	dir := autoTraceDir()
+	if dir == "" {
+		return
+	}
+	n := nextTraceRow(dir)
+	if err := appendTraceRow(dir, n, action, loc, traceReason); err != nil {
+		fmt.Fprintf(stdout, "warning: trace row not written: %v\n", err)
+		return
+	}
+	if traceReason == "" {
+		fmt.Fprintf(stdout, "trace row %d (%s) — add reasoning with: delve-helper report-annotate %d -reason \"...\"\n", n, action, n)
+	} else {
+		fmt.Fprintf(stdout, "trace row %d (%s)\n", n, action)
+	}
+}
+
+// bpTraceLoc formats a breakpoint as file:line (bp N, func).
+func bpTraceLoc(bp *api.Breakpoint) string {
+	loc := fmt.Sprintf("%s:%d (bp %d", filepath.Base(bp.File), bp.Line, bp.ID)
+	if bp.FunctionName != "" {
+		loc += ", " + bp.FunctionName
+	}
+	return loc + ")"
+}
+
+// autoTraceStop records where a continue or step stopped: a breakpoint hit,
+// a plain stop after a step, or the process exit.
+func autoTraceStop(action string, state *api.DebuggerState) {
+	if autoTraceDir() == "" || state == nil {
+		return
+	}
+	if state.Exited {
+		autoTrace("exit", fmt.Sprintf("status %d", state.ExitStatus))
+		return
+	}
+	for _, t := range state.Threads {
+		if t.Breakpoint != nil && t.Breakpoint.ID > 0 && action == "continue" {
+			fn := ""
+			if t.Function != nil {
+				fn = ", " + t.Function.Name()
+			}
+			autoTrace("hit", fmt.Sprintf("%s:%d (bp %d%s)", filepath.Base(t.File), t.Line, t.Breakpoint.ID, fn))
+			return
+		}
+	}
+	loc, fn := stopLocation(state)
+	if fn != "" {
+		loc += " (" + fn + ")"
+	}
+	if action == "continue" {
+		action = "stop"
+	}
+	autoTrace(action, loc)
+}
+
+// cmdRestart restarts the target from the beginning, keeping breakpoints.
+func cmdRestart(client *loggingClient, args []string) error {
+	rebuild := len(args) > 0 && args[0] == "-rebuild"
+	discarded, err := client.Restart(rebuild)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, "process restarted")
+	for _, d := range discarded {
+		fmt.Fprintf(stdout, "breakpoint %d discarded: %s\n", d.Breakpoint.ID, d.Reason)
+	}
+	recordStart(getDlvDir(), "restart")
+	autoTrace("restart", readSessionFile(getDlvDir(), "target"))
+	return nil
+}
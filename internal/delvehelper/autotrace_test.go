@@ -0,0 +1,31 @@
+package delvehelper
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTakeReason(t *testing.T) {
+	args := []string{"main.go:30", "-reason", "entry", "--reason=why"}
+	for _, tc := range []struct {
+		auto, dbg string
+		cmd       string
+		reason    string
+		rest      string
+	}{
+		{"", "/tmp/dbg", "break", "", "main.go:30 -reason entry --reason=why"},
+		{"0", "/tmp/dbg", "break", "", "main.go:30 -reason entry --reason=why"},
+		{"1", "", "break", "", "main.go:30 -reason entry --reason=why"},
+		{"1", "/tmp/dbg", "print", "", "main.go:30 -reason entry --reason=why"},
+		{"1", "/tmp/dbg", "break", "why", "main.go:30"},
+		{"1", "/tmp/dbg", "c", "why", "main.go:30"},
+	} {
+		t.Setenv("DLV_AUTO_TRACE", tc.auto)
+		t.Setenv("DBG_DIR", tc.dbg)
+		reason, rest := takeReason(tc.cmd, append([]string(nil), args...))
+		if reason != tc.reason || strings.Join(rest, " ") != tc.rest {
+			t.Errorf("DLV_AUTO_TRACE=%q DBG_DIR=%q %s: reason %q, args %q; want %q, %q",
+				tc.auto, tc.dbg, tc.cmd, reason, rest, tc.reason, tc.rest)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// Debuggable builds: inspect a binary's build info and DWARF for
+// optimization, stripping and -trimpath, and build one with the right flags.
+package delvehelper
+
+import (
+	"debug/buildinfo"
+	"debug/dwarf"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// debugGCFlags disables optimizations and inlining in every package.
+const debugGCFlags = "all=-N -l"
+
+// openDWARF returns the DWARF data of an ELF, Mach-O or PE binary.
+func openDWARF(path string) (*dwarf.Data, error) {
+	if f, err := elf.Open(path); err == nil {
+		defer f.Close()
+		return f.DWARF()
+	}
+	if f, err := macho.Open(path); err == nil {
+		defer f.Close()
+		return f.DWARF()
+	}
+	if f, err := pe.Open(path); err == nil {
+		defer f.Close()
+		return f.DWARF()
+	}
+	return nil, fmt.Errorf("%s: unrecognized executable format", path)
+}
+
+// optimizedUnits returns the compile units of the main module whose DWARF
+// producer lacks -N or -l, i.e. packages compiled with optimizations or inlining.
+func optimizedUnits(d *dwarf.Data, modPath string) []string {
+	var units []string
+	r := d.Reader()
+	for {
+		e, err := r.Next()
+		if err != nil || e == nil {
+			break
+		}
+		if e.Tag != dwarf.TagCompileUnit {
+			continue
+		}
+		r.SkipChildren()
+		name, _ := e.Val(dwarf.AttrName).(string)
+		producer, _ := e.Val(dwarf.AttrProducer).(string)
+		inModule := name == "main" || (modPath != "" && (name == modPath || strings.HasPrefix(name, modPath+"/")))
+		if !inModule || !strings.HasPrefix(producer, "Go cmd/compile") {
+			continue
+		}
+		flags := strings.Fields(producer[strings.Index(producer, ";")+1:])
+		hasN, hasL := false, false
+		for _, f := range flags {
+			hasN = hasN || f == "-N"
+			hasL = hasL || f == "-l"
+		}
+		if !hasN || !hasL {
+			units = append(units, name)
+		}
+	}
+	return units
+}
+
+// inspectBinary returns human-readable warnings about properties of the
+// binary at path that make debugging unreliable. An error means the file is
+// not a Go binary that can be inspected.
+func inspectBinary(path string) ([]string, error) {
+	bi, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read Go build info from %s: %w", path, err)
+	}
+	var warnings []string
+	for _, s := range bi.Settings {
+		if s.Key == "-trimpath" && s.Value == "true" {
+			warnings = append(warnings, "built with -trimpath: source paths in the binary are module-relative, "+
+				"so file:line breakpoints need -substitute-path (or rebuild with 'delve-helper build')")
+		}
+	}
+	d, err := openDWARF(path)
+	if err != nil {
+		return append(warnings, "no DWARF debug info (stripped, e.g. -ldflags=-w or -s): "+
+			"breakpoints, locals and stack traces will not work; rebuild with 'delve-helper build'"), nil
+	}
+	if units := optimizedUnits(d, bi.Main.Path); len(units) > 0 {
+		shown := units
+		if len(shown) > 3 {
+			shown = append(shown[:3:3], "...")
+		}
+		warnings = append(warnings, fmt.Sprintf("optimized build (no -gcflags='%s'; affects %s): "+
+			"variables may be unavailable and stepping may skip lines; rebuild with 'delve-helper build'",
+			debugGCFlags, strings.Join(shown, ", ")))
+	}
+	return warnings, nil
+}
+
+// warnBinary prints inspectBinary's findings to stderr without failing.
+func warnBinary(path string) {
+	warnings, err := inspectBinary(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		return
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s: %s\n", path, w)
+	}
+}
+
+// withoutTrimpath drops -trimpath from a GOFLAGS value so paths in the
+// binary match the local checkout.
+func withoutTrimpath(goflags string) string {
+	var keep []string
+	for _, f := range strings.Fields(goflags) {
+		if f != "-trimpath" && f != "-trimpath=true" {
+			keep = append(keep, f)
+		}
+	}
+	return strings.Join(keep, " ")
+}
+
+// cmdBuild compiles pkg with optimizations and inlining disabled into a temp
+// dir, checks the result, and starts it with start -exec.
+func cmdBuild(args []string) error {
+	fs := flag.NewFlagSet("build", flag.ContinueOnError)
+	tags := fs.String("tags", "", "comma-separated build tags")
+	out := fs.String("o", "", "output path (default: a temp dir)")
+	noStart := fs.Bool("no-start", false, "only build and check the binary; do not start a session")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	rest := fs.Args()
+	pkg := "."
+	if len(rest) > 0 && rest[0] != "--" {
+		pkg, rest = rest[0], rest[1:]
+	}
+	if len(rest) > 0 && rest[0] == "--" {
+		rest = rest[1:]
+	}
+
+	bin := *out
+	if bin == "" {
+		dir, err := os.MkdirTemp("", "delve-build-")
+		if err != nil {
+			return err
+		}
+		bin = filepath.Join(dir, "debug_bin")
+	}
+	bin, err := filepath.Abs(bin)
+	if err != nil {
+		return err
+	}
+
+	goArgs := []string{"build", "-gcflags=" + debugGCFlags, "-o", bin}
+	if *tags != "" {
+		goArgs = append(goArgs, "-tags="+*tags)
+	}
+	// Like start: a target directory that is its own module is built from inside it.
+	buildDir, target := "", pkg
+	if pkg != "." {
+		if _, err := os.Stat(filepath.Join(pkg, "go.mod")); err == nil {
+			buildDir, target = pkg, "."
+		}
+	}
+	goArgs = append(goArgs, target)
+	cmd := exec.Command("go", goArgs...)
+	cmd.Dir = buildDir
+	cmd.Env = append(os.Environ(), "GOFLAGS="+withoutTrimpath(os.Getenv("GOFLAGS")))
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	fmt.Printf("go %s\n", strings.Join(goArgs, " "))
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("go build failed: %w", err)
+	}
+	fmt.Println("built", bin)
+	if *noStart {
+		warnBinary(bin)
+		return nil
+	}
+	// start -exec re-checks the binary and warns if the flags did not take.
+	return cmdStart(append([]string{"-exec", bin, "--"}, rest...))
+}
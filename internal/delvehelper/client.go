@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/go-delve/delve/service/api"
 	"github.com/go-delve/delve/service/rpc2"
@@ -42,6 +44,21 @@ func newRPCLogger() (*rpcLogger, error) {
 	return l, nil
 }
 
+// lastState is the summary of the last debugger state an RPC call returned
+// in this process; the transcript records it after each command.
+var lastState string
+
+// result logs the state an RPC call returned and remembers it in lastState.
+func (l *rpcLogger) result(call string, state *api.DebuggerState, err error) {
+	switch {
+	case state != nil:
+		lastState = summarizeState(state)
+	case isExitError(err):
+		lastState = "exited"
+	}
+	l.Debug(call+" result", "state", summarizeState(state), "err", err)
+}
+
 func (l *rpcLogger) close() {
 	if l.file != nil {
 		l.Info("logging closed")
@@ -51,7 +68,8 @@ func (l *rpcLogger) close() {
 
 type loggingClient struct {
 	*rpc2.RPCClient
-	log *rpcLogger
+	log    *rpcLogger
+	closed bool // set once the connection has been handed back (see ContinueAsync)
 }
 
 func summarizeState(state *api.DebuggerState) string {
@@ -81,7 +99,14 @@ func summarizeState(state *api.DebuggerState) string {
 func (c *loggingClient) GetState() (*api.DebuggerState, error) {
 	c.log.Debug("GetState")
 	state, err := c.RPCClient.GetState()
-	c.log.Debug("GetState result", "state", summarizeState(state), "err", err)
+	c.log.result("GetState", state, err)
+	return state, err
+}
+
+func (c *loggingClient) GetStateNonBlocking() (*api.DebuggerState, error) {
+	c.log.Debug("GetStateNonBlocking")
+	state, err := c.RPCClient.GetStateNonBlocking()
+	c.log.result("GetStateNonBlocking", state, err)
 	return state, err
 }
 
@@ -127,33 +152,62 @@ func (c *loggingClient) Continue() <-chan *api.DebuggerState {
 	out := make(chan *api.DebuggerState, 1)
 	go func() {
 		state := <-ch
-		c.log.Debug("Continue result", "state", summarizeState(state), "err", state.Err)
+		c.log.result("Continue", state, state.Err)
 		out <- state
 	}()
 	return out
 }
 
+// ContinueAsync resumes the target and closes the connection without waiting
+// for it to stop; the target keeps running and a later client can halt it or
+// wait for the next stop.
+func (c *loggingClient) ContinueAsync() error {
+	c.log.Debug("ContinueAsync")
+	err := c.RPCClient.Disconnect(true)
+	c.closed = true
+	if err == nil {
+		lastState = "running"
+	}
+	c.log.Debug("ContinueAsync result", "err", err)
+	c.log.close()
+	return err
+}
+
+func (c *loggingClient) Halt() (*api.DebuggerState, error) {
+	c.log.Debug("Halt")
+	state, err := c.RPCClient.Halt()
+	c.log.result("Halt", state, err)
+	return state, err
+}
+
 func (c *loggingClient) Next() (*api.DebuggerState, error) {
 	c.log.Debug("Next")
 	state, err := c.RPCClient.Next()
-	c.log.Debug("Next result", "state", summarizeState(state), "err", err)
+	c.log.result("Next", state, err)
 	return state, err
 }
 
 func (c *loggingClient) Step() (*api.DebuggerState, error) {
 	c.log.Debug("Step")
 	state, err := c.RPCClient.Step()
-	c.log.Debug("Step result", "state", summarizeState(state), "err", err)
+	c.log.result("Step", state, err)
 	return state, err
 }
 
 func (c *loggingClient) StepOut() (*api.DebuggerState, error) {
 	c.log.Debug("StepOut")
 	state, err := c.RPCClient.StepOut()
-	c.log.Debug("StepOut result", "state", summarizeState(state), "err", err)
+	c.log.result("StepOut", state, err)
 	return state, err
 }
 
+func (c *loggingClient) Restart(rebuild bool) ([]api.DiscardedBreakpoint, error) {
+	c.log.Debug("Restart", "rebuild", rebuild)
+	discarded, err := c.RPCClient.Restart(rebuild)
+	c.log.Debug("Restart result", "discarded", len(discarded), "err", err)
+	return discarded, err
+}
+
 func (c *loggingClient) EvalVariable(scope api.EvalScope, expr string, cfg api.LoadConfig) (*api.Variable, error) {
 	c.log.Debug("EvalVariable", "expr", expr)
 	v, err := c.RPCClient.EvalVariable(scope, expr, cfg)
@@ -193,7 +247,20 @@ func (c *loggingClient) ListGoroutines(start int, count int) ([]*api.Goroutine,
 	return goroutines, next, err
 }
 
+func (c *loggingClient) Detach(kill bool) error {
+	c.log.Debug("Detach", "kill", kill)
+	err := c.RPCClient.Detach(kill)
+	c.closed = true
+	c.log.Debug("Detach result", "err", err)
+	c.log.close()
+	return err
+}
+
 func (c *loggingClient) Disconnect(cont bool) error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
 	c.log.Debug("Disconnect", "cont", cont)
 	err := c.RPCClient.Disconnect(cont)
 	c.log.Debug("Disconnect result", "err", err)
@@ -217,17 +284,52 @@ func getAddr() (string, error) {
 	return strings.TrimSpace(string(b)), nil
 }
 
+// newClient connects to the current session. Sessions recorded in .dlv/ are
+// checked first: if their dlv process died or its pid now belongs to another
+// process, the stale files are removed and an explanatory error is returned.
+// The same happens when a session without a pid file (connect, or a lost
+// pid file) is unreachable. A failed dial to a live dlv is returned as is and
+// leaves the files alone.
 func newClient() (*loggingClient, error) {
 	addr, err := getAddr()
 	if err != nil {
 		return nil, err
 	}
+	if os.Getenv("DLV_ADDR") != "" {
+		return newClientAt(addr)
+	}
+	dlvDir := getDlvDir()
+	if reason := sessionProblem(dlvDir); reason != "" {
+		return nil, cleanStaleSession(dlvDir, reason)
+	}
+	client, err := newClientAt(addr)
+	if err != nil {
+		// dlv may have exited since the check above.
+		if reason := sessionProblem(dlvDir); reason != "" {
+			return nil, cleanStaleSession(dlvDir, reason)
+		}
+		if _, ok := readSessionPid(dlvDir); !ok && isUnreachable(err) {
+			return nil, cleanStaleSession(dlvDir, fmt.Sprintf("address %s is unreachable", addr))
+		}
+		return nil, err
+	}
+	return client, nil
+}
+
+// newClientAt connects to the Delve server at addr. It dials itself instead of
+// using rpc2.NewClient, which exits the process when the dial fails.
+func newClientAt(addr string) (*loggingClient, error) {
+	conn, err := net.DialTimeout("tcp", addr, 3*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("connect to delve at %s: %w", addr, err)
+	}
 	log, err := newRPCLogger()
 	if err != nil {
+		conn.Close()
 		return nil, err
 	}
 	log.Debug("NewClient", "addr", addr)
-	return &loggingClient{RPCClient: rpc2.NewClient(addr), log: log}, nil
+	return &loggingClient{RPCClient: rpc2.NewClientFromConn(conn), log: log}, nil
 }
 
 func scopeFromState(state *api.DebuggerState) api.EvalScope {
@@ -0,0 +1,42 @@
+package delvehelper
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// A session without a pid file (as connect records it) whose address no
+// longer answers is cleaned up like one whose dlv died.
+func TestNewClientUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	t.Setenv("DBG_DIR", t.TempDir())
+	t.Setenv("DLV_ADDR", "")
+	t.Setenv("DLV_SESSION", "")
+	dlvDir := getDlvDir()
+	if err := os.MkdirAll(dlvDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"addr": addr + "\n", "target": "connect " + addr + "\n"} {
+		if err := os.WriteFile(filepath.Join(dlvDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err = newClient()
+	if err == nil || !strings.Contains(err.Error(), "stale delve session") || !strings.Contains(err.Error(), addr+" is unreachable") {
+		t.Fatalf("newClient: err = %v, want a stale session error", err)
+	}
+	for _, name := range []string{"addr", "target"} {
+		if _, err := os.Stat(filepath.Join(dlvDir, name)); !os.IsNotExist(err) {
+			t.Errorf(".dlv/%s not removed", name)
+		}
+	}
+}
@@ -2,20 +2,22 @@
 package delvehelper
 
 import (
+	"flag"
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-delve/delve/service/api"
 )
 
 func printState(state *api.DebuggerState) error {
 	if state.Exited {
-		fmt.Printf("Process exited with status %d\n", state.ExitStatus)
+		fmt.Fprintf(stdout, "Process exited with status %d\n", state.ExitStatus)
 		return nil
 	}
 	if state.Running {
-		fmt.Println("Process is running.")
+		fmt.Fprintln(stdout, "Process is running.")
 		return nil
 	}
 	printed := false
@@ -28,20 +30,20 @@ func printState(state *api.DebuggerState) error {
 		if loc.Function != nil {
 			fn = loc.Function.Name()
 		}
-		fmt.Printf("goroutine %d at %s:%d (%s)\n",
+		fmt.Fprintf(stdout, "goroutine %d at %s:%d (%s)\n",
 			state.SelectedGoroutine.ID, loc.File, loc.Line, fn)
 		printed = true
 	}
 	for _, t := range state.Threads {
 		if t.Breakpoint != nil {
-			fmt.Printf("  thread %d at breakpoint %d: %s:%d\n",
+			fmt.Fprintf(stdout, "  thread %d at breakpoint %d: %s:%d\n",
 				t.ID, t.Breakpoint.ID, t.File, t.Line)
 			printed = true
 		}
 	}
 	// Fix #4: always emit something so the agent knows the session is live.
 	if !printed {
-		fmt.Println("stopped")
+		fmt.Fprintln(stdout, "stopped")
 	}
 	return nil
 }
@@ -61,7 +63,7 @@ func cmdBreak(client *loggingClient, state *api.DebuggerState, args []string) er
 	}
 
 	scope := scopeFromState(state)
-	locs, _, err := client.FindLocation(scope, locspec, false, nil)
+	locs, _, err := client.FindLocation(scope, locspec, false, loadSubstituteRules())
 	if err != nil {
 		return err
 	}
@@ -85,7 +87,12 @@ func cmdBreak(client *loggingClient, state *api.DebuggerState, args []string) er
 		if cond != "" {
 			msg += fmt.Sprintf(" if %s", cond)
 		}
-		fmt.Println(msg)
+		fmt.Fprintln(stdout, msg)
+		traceLoc := bpTraceLoc(created)
+		if cond != "" {
+			traceLoc += " if " + cond
+		}
+		autoTrace("set", traceLoc)
 	}
 	return nil
 }
@@ -103,7 +110,7 @@ func cmdBreakpoints(client *loggingClient) error {
 		if bp.Disabled {
 			dis = " (disabled)"
 		}
-		fmt.Printf("%d: %s:%d%s\n", bp.ID, bp.File, bp.Line, dis)
+		fmt.Fprintf(stdout, "%d: %s:%d%s\n", bp.ID, bp.File, bp.Line, dis)
 	}
 	return nil
 }
@@ -116,11 +123,14 @@ func cmdClear(client *loggingClient, args []string) error {
 	if err != nil {
 		return err
 	}
-	_, err = client.ClearBreakpoint(id)
+	bp, err := client.ClearBreakpoint(id)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("cleared breakpoint %d\n", id)
+	fmt.Fprintf(stdout, "cleared breakpoint %d\n", id)
+	if bp != nil {
+		autoTrace("clear", bpTraceLoc(bp))
+	}
 	return nil
 }
 
@@ -131,21 +141,110 @@ func isExitError(err error) bool {
 	return err != nil && strings.Contains(err.Error(), "has exited with status")
 }
 
-func cmdContinue(client *loggingClient) error {
+func cmdContinue(client *loggingClient, args []string) error {
+	fs := flag.NewFlagSet("continue", flag.ContinueOnError)
+	timeout := fs.Duration("timeout", 0, "halt the target if it has not stopped after this long (e.g. 10s)")
+	async := fs.Bool("async", false, "resume and return immediately; collect the stop later with wait or halt")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *async {
+		if err := client.ContinueAsync(); err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, "Process is running. Use 'delve-helper wait' to collect the next stop or 'delve-helper halt' to stop it.")
+		return nil
+	}
+
 	ch := client.Continue()
-	state := <-ch
+	var state *api.DebuggerState
+	if *timeout > 0 {
+		select {
+		case state = <-ch:
+		case <-time.After(*timeout):
+			fmt.Fprintf(stdout, "no stop after %s; halting\n", *timeout)
+			if _, err := client.Halt(); err != nil {
+				return fmt.Errorf("halt: %w", err)
+			}
+			state = <-ch
+			if state.Err == nil && !state.Exited {
+				if err := printStop(client, state); err != nil {
+					return err
+				}
+				autoTraceStop("halt", state)
+				fmt.Fprintln(stdout, "goroutines at halt:")
+				return cmdGoroutines(client)
+			}
+		}
+	} else {
+		state = <-ch
+	}
 	if state.Err != nil {
 		if isExitError(state.Err) {
-			fmt.Println(state.Err)
+			fmt.Fprintln(stdout, state.Err)
+			autoTrace("exit", state.Err.Error())
 			return nil
 		}
 		return state.Err
 	}
 	if state.Exited {
-		fmt.Printf("Process exited with status %d\n", state.ExitStatus)
+		fmt.Fprintf(stdout, "Process exited with status %d\n", state.ExitStatus)
+		autoTraceStop("continue", state)
 		return nil
 	}
-	return printState(state)
+	if err := printStop(client, state); err != nil {
+		return err
+	}
+	autoTraceStop("continue", state)
+	return nil
+}
+
+// cmdHalt stops a target left running by continue -async.
+func cmdHalt(client *loggingClient, state *api.DebuggerState) error {
+	if !state.Running {
+		fmt.Fprintln(stdout, "process is not running")
+		return printState(state)
+	}
+	if _, err := client.Halt(); err != nil {
+		return err
+	}
+	return waitForStop(client, 5*time.Second)
+}
+
+// cmdWait blocks until a running target stops and prints where it stopped.
+func cmdWait(client *loggingClient, args []string) error {
+	fs := flag.NewFlagSet("wait", flag.ContinueOnError)
+	timeout := fs.Duration("timeout", 0, "give up after this long (default: wait forever)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return waitForStop(client, *timeout)
+}
+
+// waitForStop polls the non-blocking state until the target is no longer
+// running (timeout <= 0 waits forever), then prints the state.
+func waitForStop(client *loggingClient, timeout time.Duration) error {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	for {
+		state, err := client.GetStateNonBlocking()
+		if isExitError(err) {
+			fmt.Fprintln(stdout, err)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !state.Running {
+			return printStop(client, state)
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return fmt.Errorf("process still running after %s (use 'delve-helper halt' to stop it)", timeout)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
 }
 
 func cmdStep(client *loggingClient, name string) error {
@@ -161,18 +260,25 @@ func cmdStep(client *loggingClient, name string) error {
 	default:
 		return fmt.Errorf("unknown step command: %s", name)
 	}
+	action := strings.ToLower(name)
 	if isExitError(err) {
-		fmt.Println(err)
+		fmt.Fprintln(stdout, err)
+		autoTrace("exit", err.Error())
 		return nil
 	}
 	if err != nil {
 		return err
 	}
 	if state.Exited {
-		fmt.Printf("Process exited with status %d\n", state.ExitStatus)
+		fmt.Fprintf(stdout, "Process exited with status %d\n", state.ExitStatus)
+		autoTraceStop(action, state)
 		return nil
 	}
-	return printState(state)
+	if err := printStop(client, state); err != nil {
+		return err
+	}
+	autoTraceStop(action, state)
+	return nil
 }
 
 func cmdPrint(client *loggingClient, state *api.DebuggerState, args []string) error {
@@ -189,7 +295,8 @@ func cmdPrint(client *loggingClient, state *api.DebuggerState, args []string) er
 	if v == nil {
 		return fmt.Errorf("expression evaluated to nothing")
 	}
-	fmt.Printf("%s = %s\n", v.Name, v.Value)
+	fmt.Fprintf(stdout, "%s = %s\n", v.Name, v.Value)
+	recordValues(state, "print", []api.Variable{*v})
 	return nil
 }
 
@@ -201,8 +308,9 @@ func cmdLocals(client *loggingClient, state *api.DebuggerState) error {
 		return err
 	}
 	for _, v := range vars {
-		fmt.Printf("%s = %s\n", v.Name, v.Value)
+		fmt.Fprintf(stdout, "%s = %s\n", v.Name, v.Value)
 	}
+	recordValues(state, "locals", vars)
 	return nil
 }
 
@@ -214,8 +322,9 @@ func cmdArgs(client *loggingClient, state *api.DebuggerState) error {
 		return err
 	}
 	for _, v := range vars {
-		fmt.Printf("%s = %s\n", v.Name, v.Value)
+		fmt.Fprintf(stdout, "%s = %s\n", v.Name, v.Value)
 	}
+	recordValues(state, "args", vars)
 	return nil
 }
 
@@ -233,7 +342,7 @@ func cmdStack(client *loggingClient, state *api.DebuggerState) error {
 		if f.Function != nil {
 			fn = f.Function.Name()
 		}
-		fmt.Printf("#%d %s %s:%d\n", i, fn, f.File, f.Line)
+		fmt.Fprintf(stdout, "#%d %s %s:%d\n", i, fn, f.File, f.Line)
 	}
 	return nil
 }
@@ -252,7 +361,7 @@ func cmdGoroutines(client *loggingClient) error {
 		if loc.Function != nil {
 			fn = loc.Function.Name()
 		}
-		fmt.Printf("goroutine %d [%s:%d %s]\n", g.ID, loc.File, loc.Line, fn)
+		fmt.Fprintf(stdout, "goroutine %d [%s:%d %s]\n", g.ID, loc.File, loc.Line, fn)
 	}
 	return nil
 }
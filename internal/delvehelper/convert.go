@@ -14,24 +14,33 @@ import (
 // fixMarkdownTables inserts missing header-separator rows so Pandoc recognizes tables.
 // Markdown tables require: header row, separator row (| --- | --- |), then data rows.
 // A separator is only injected after the FIRST row of a new table; rows inside an
-// existing table body are left untouched.
+// existing table body are left untouched, and so are lines inside fenced code.
 func fixMarkdownTables(md string) string {
 	lines := strings.Split(md, "\n")
 	var out []string
 	inTable := false // true while consecutive | rows are being processed
+	fence := ""      // opening fence of the code block being copied
 	for i := 0; i < len(lines); i++ {
 		line := lines[i]
 		out = append(out, line)
+		if fence != "" {
+			if closesFence(line, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if m := mdFenceRE.FindStringSubmatch(line); m != nil {
+			fence = m[1]
+			inTable = false
+			continue
+		}
 		isTableRow := strings.HasPrefix(line, "|") && strings.Contains(line[1:], "|")
 		isSeparator := isTableRow && strings.Contains(line, "---")
 		if isTableRow && !inTable && i+1 < len(lines) {
 			// First row of a potential new table — insert separator only if missing.
 			next := lines[i+1]
 			if strings.HasPrefix(next, "|") && !strings.Contains(next, "---") {
-				nc := strings.Count(line, "|") - 1
-				if nc < 1 {
-					nc = 1
-				}
+				nc := len(splitTableRow(line))
 				sep := "|"
 				for j := 0; j < nc; j++ {
 					sep += " --- |"
@@ -73,24 +82,7 @@ func fixLongtable(latex []byte) []byte {
 		if ncols == 0 {
 			ncols = 4 // last-resort fallback
 		}
-		var spec string
-		switch ncols {
-		case 2:
-			spec = `@{}p{0.38\linewidth}p{0.52\linewidth}@{}`
-		case 3:
-			spec = `@{}p{0.22\linewidth}p{0.28\linewidth}p{0.40\linewidth}@{}`
-		case 4:
-			spec = `@{}p{1.5cm}p{2.2cm}p{3.8cm}p{0.35\linewidth}@{}`
-		default:
-			w := 0.88 / float64(ncols)
-			var sb strings.Builder
-			sb.WriteString("@{}")
-			for i := 0; i < ncols; i++ {
-				fmt.Fprintf(&sb, `p{%.2f\linewidth}`, w)
-			}
-			sb.WriteString("@{}")
-			spec = sb.String()
-		}
+		spec := longtableSpec(ncols)
 		return `\begin{longtable}{` + spec + `}` + "\n" + `\toprule` + "\n" + `\noalign{}`
 	})
 
@@ -133,14 +125,76 @@ func wrapStyledSections(latex []byte) []byte {
 	return []byte(s)
 }
 
-// MDToTex reads .md files from dbgDir, converts to LaTeX via pandoc, applies
-// styled boxes (rootcausebox, fixbox), and returns the full document content.
+// Renderers for MDToTex. auto uses pandoc when it is on PATH and the native
+// Go renderer otherwise.
+const (
+	rendererAuto   = "auto"
+	rendererNative = "native"
+	rendererPandoc = "pandoc"
+)
+
+// MDToTex reads .md files from dbgDir, converts them to LaTeX with renderer,
+// applies styled boxes (rootcausebox, fixbox), and returns the full document content.
 // pkg and date substitute <package> and <YYYY-MM-DD> in the template.
-func MDToTex(dbgDir, pkg, date string) (tex string, mdCount int, err error) {
+func MDToTex(dbgDir, pkg, date, renderer string) (tex string, mdCount int, err error) {
+	mdStr, mdCount, err := readReportMarkdown(dbgDir)
+	if err != nil {
+		return "", 0, err
+	}
+	mdStr = fixMarkdownTables(mdStr)
+
+	var latexBody []byte
+	switch renderer {
+	case rendererAuto, "":
+		if _, err := exec.LookPath("pandoc"); err != nil {
+			latexBody = renderLatex(mdStr)
+			break
+		}
+		fallthrough
+	case rendererPandoc:
+		if latexBody, err = pandocLatex(mdStr); err != nil {
+			return "", 0, err
+		}
+	case rendererNative:
+		latexBody = renderLatex(mdStr)
+	default:
+		return "", 0, fmt.Errorf("unknown renderer %q (want auto, native or pandoc)", renderer)
+	}
+	latexBody = wrapStyledSections(latexBody)
+
+	tpl, err := templateFS.ReadFile("templates/tex/debug_report_template_md.tex")
+	if err != nil {
+		return "", 0, fmt.Errorf("read template: %w", err)
+	}
+	if !strings.Contains(string(tpl), "%%MD_BODY%%") {
+		return "", 0, fmt.Errorf("template missing %%MD_BODY%% placeholder")
+	}
+	out := strings.Replace(string(tpl), "%%MD_BODY%%", string(latexBody), 1)
+	if pkg != "" {
+		out = strings.ReplaceAll(out, "<package>", pkg)
+	}
+	if date != "" {
+		out = strings.ReplaceAll(out, "<YYYY-MM-DD>", date)
+	}
+	return out, mdCount, nil
+}
+
+// readReportMarkdown concatenates the report fragments of dbgDir in name
+// order, skipping templates (frag_*.md) and the evidence checklist. When the
+// dir has a report.json, the fragments it owns are rendered from it.
+func readReportMarkdown(dbgDir string) (string, int, error) {
 	entries, err := os.ReadDir(dbgDir)
 	if err != nil {
 		return "", 0, fmt.Errorf("read dir %s: %w", dbgDir, err)
 	}
+	m, hasModel, err := loadReport(dbgDir)
+	if err != nil {
+		return "", 0, err
+	}
+	var owned map[string]string
+	if hasModel {
+		owned = m.fragments()
+	}
 	var mdFiles []string
 	for _, entry := range entries {
 		if entry.IsDir() {
@@ -150,6 +204,9 @@ func MDToTex(dbgDir, pkg, date string) (tex string, mdCount int, err error) {
 		if strings.HasPrefix(name, ".") {
 			continue
 		}
+		if _, ok := owned[name]; ok {
+			continue
+		}
 		if strings.HasSuffix(strings.ToLower(name), ".md") {
 			// Exclude template fragments (frag_*.md) — they contain placeholders, not report content
 			if strings.HasPrefix(name, "frag_") {
@@ -162,6 +219,11 @@ func MDToTex(dbgDir, pkg, date string) (tex string, mdCount int, err error) {
 			mdFiles = append(mdFiles, name)
 		}
 	}
+	for name, content := range owned {
+		if content != "" {
+			mdFiles = append(mdFiles, name)
+		}
+	}
 	if len(mdFiles) == 0 {
 		return "", 0, fmt.Errorf("no .md files found in %s", dbgDir)
 	}
@@ -172,60 +234,49 @@ func MDToTex(dbgDir, pkg, date string) (tex string, mdCount int, err error) {
 		if i > 0 {
 			mdBody.WriteString("\n\n")
 		}
+		if content, ok := owned[name]; ok {
+			mdBody.WriteString(content)
+			continue
+		}
 		content, err := os.ReadFile(filepath.Join(dbgDir, name))
 		if err != nil {
 			return "", 0, fmt.Errorf("read %s: %w", name, err)
 		}
 		mdBody.Write(content)
 	}
+	return mdBody.String(), len(mdFiles), nil
+}
 
-	mdStr := mdBody.String()
-	mdStr = fixMarkdownTables(mdStr)
-
+// pandocLatex converts Markdown to a LaTeX body with pandoc and the minted
+// Lua filter.
+func pandocLatex(md string) ([]byte, error) {
 	if _, err := exec.LookPath("pandoc"); err != nil {
-		return "", 0, fmt.Errorf("pandoc is required to convert markdown to LaTeX: %w", err)
+		return nil, fmt.Errorf("pandoc is required to convert markdown to LaTeX (or use -renderer=native): %w", err)
 	}
 	mintedFilter, err := templateFS.ReadFile("templates/lua/minted.lua")
 	if err != nil {
-		return "", 0, fmt.Errorf("read minted filter: %w", err)
+		return nil, fmt.Errorf("read minted filter: %w", err)
 	}
 	filterFile, err := os.CreateTemp("", "delve-minted-*.lua")
 	if err != nil {
-		return "", 0, fmt.Errorf("create temp filter: %w", err)
+		return nil, fmt.Errorf("create temp filter: %w", err)
 	}
 	defer os.Remove(filterFile.Name())
 	if _, err := filterFile.Write(mintedFilter); err != nil {
 		filterFile.Close()
-		return "", 0, fmt.Errorf("write minted filter: %w", err)
+		return nil, fmt.Errorf("write minted filter: %w", err)
 	}
 	if err := filterFile.Close(); err != nil {
-		return "", 0, fmt.Errorf("close minted filter: %w", err)
+		return nil, fmt.Errorf("close minted filter: %w", err)
 	}
 	pandoc := exec.Command("pandoc", "-f", "markdown", "-t", "latex", "--wrap=preserve",
 		"--lua-filter="+filterFile.Name())
-	pandoc.Stdin = strings.NewReader(mdStr)
+	pandoc.Stdin = strings.NewReader(md)
 	latexBody, err := pandoc.Output()
 	if err != nil {
-		return "", 0, fmt.Errorf("pandoc failed: %w", err)
-	}
-	latexBody = fixLongtable(latexBody)
-	latexBody = wrapStyledSections(latexBody)
-
-	tpl, err := templateFS.ReadFile("templates/tex/debug_report_template_md.tex")
-	if err != nil {
-		return "", 0, fmt.Errorf("read template: %w", err)
-	}
-	if !strings.Contains(string(tpl), "%%MD_BODY%%") {
-		return "", 0, fmt.Errorf("template missing %%MD_BODY%% placeholder")
-	}
-	out := strings.Replace(string(tpl), "%%MD_BODY%%", string(latexBody), 1)
-	if pkg != "" {
-		out = strings.ReplaceAll(out, "<package>", pkg)
-	}
-	if date != "" {
-		out = strings.ReplaceAll(out, "<YYYY-MM-DD>", date)
+		return nil, fmt.Errorf("pandoc failed: %w", err)
 	}
-	return out, len(mdFiles), nil
+	return fixLongtable(latexBody), nil
 }
 
 // ensureReportTemplates copies preamble (and styles) from embedded templates to dbgDir
@@ -0,0 +1,311 @@
+// Session daemon: keeps one RPC connection to Delve open and serves session
+// commands over a Unix socket in .dlv/, so each CLI call skips the
+// connect/GetState/Disconnect round trip.
+package delvehelper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/rpc"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// daemonShutdown is the request command that asks the daemon to exit.
+const daemonShutdown = "daemon-shutdown"
+
+// daemonEnv lists the environment variables session commands read. The
+// daemon's own environment is frozen when it is spawned, so clients send
+// their values with each request.
+var daemonEnv = []string{"DBG_DIR", "DLV_AUTO_TRACE", "DLV_SESSION"}
+
+// daemonRequest is one command sent to the daemon. Dir and Env carry the
+// client's working directory and daemonEnv values (an empty value means
+// unset); when they are empty the daemon runs in its own.
+type daemonRequest struct {
+	Cmd  string            `json:"cmd"`
+	Args []string          `json:"args"`
+	Dir  string            `json:"dir,omitempty"`
+	Env  map[string]string `json:"env,omitempty"`
+}
+
+// newDaemonRequest returns a request for cmd carrying the caller's working
+// directory and daemonEnv values.
+func newDaemonRequest(cmd string, args []string) daemonRequest {
+	req := daemonRequest{Cmd: cmd, Args: args, Env: map[string]string{}}
+	req.Dir, _ = os.Getwd()
+	for _, k := range daemonEnv {
+		req.Env[k] = os.Getenv(k)
+	}
+	return req
+}
+
+type daemonResponse struct {
+	Output string `json:"output"`
+	Err    string `json:"err,omitempty"`
+	State  string `json:"state,omitempty"` // lastState after the command
+}
+
+func getDaemonSockPath() string {
+	return filepath.Join(getDlvDir(), "daemon.sock")
+}
+
+// cmdDaemon starts the daemon in the background (default), serves in the
+// foreground (-serve, used by the background child), or stops it (-stop).
+func cmdDaemon(args []string) error {
+	fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	serve := fs.Bool("serve", false, "serve in the foreground (used internally)")
+	stop := fs.Bool("stop", false, "stop a running daemon")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	switch {
+	case *stop:
+		if !stopDaemon(getDaemonSockPath()) {
+			fmt.Println("no daemon running")
+		}
+		return nil
+	case *serve:
+		return serveDaemon()
+	}
+
+	sock := getDaemonSockPath()
+	if conn, err := net.DialTimeout("unix", sock, time.Second); err == nil {
+		conn.Close()
+		fmt.Println("daemon already running on", sock)
+		return nil
+	}
+	// Fail early with the usual "no DLV_ADDR" message rather than from the child.
+	if _, err := getAddr(); err != nil {
+		return err
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locate delve-helper executable: %w", err)
+	}
+	dlvDir := getDlvDir()
+	if err := os.MkdirAll(dlvDir, 0755); err != nil {
+		return err
+	}
+	logFile, err := os.OpenFile(filepath.Join(dlvDir, "daemon.log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("open daemon log: %w", err)
+	}
+	defer logFile.Close()
+	cmd := exec.Command(self, "daemon", "-serve")
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	if err := startDetached(cmd); err != nil {
+		return fmt.Errorf("start daemon: %w", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if conn, err := net.DialTimeout("unix", sock, time.Second); err == nil {
+			conn.Close()
+			_ = os.WriteFile(filepath.Join(dlvDir, "daemon.pid"), []byte(strconv.Itoa(cmd.Process.Pid)+"\n"), 0644)
+			fmt.Printf("daemon started (pid %d), listening on %s\n", cmd.Process.Pid, sock)
+			return nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return fmt.Errorf("timed out waiting for daemon socket %s (see %s)", sock, logFile.Name())
+}
+
+// daemon owns the persistent RPC connection. Requests are served one at a
+// time because session commands write to the package-level stdout.
+type daemon struct {
+	client *loggingClient
+	addr   string
+}
+
+// connect returns the cached client, reconnecting when there is none or when
+// .dlv/addr now points at a different Delve instance (e.g. after a restart),
+// or when the connection was handed back by continue -async.
+func (d *daemon) connect() (*loggingClient, error) {
+	addr, err := getAddr()
+	if err != nil {
+		return nil, err
+	}
+	if d.client != nil && !d.client.closed && d.addr == addr {
+		return d.client, nil
+	}
+	d.close()
+	client, err := newClient()
+	if err != nil {
+		return nil, err
+	}
+	d.client, d.addr = client, addr
+	return client, nil
+}
+
+func (d *daemon) close() {
+	if d.client != nil {
+		_ = d.client.Disconnect(false)
+		d.client = nil
+	}
+}
+
+func (d *daemon) exec(req daemonRequest) daemonResponse {
+	var buf bytes.Buffer
+	lastState = ""
+	err := withRequestEnv(req, func() error {
+		client, err := d.connect()
+		if err != nil {
+			return err
+		}
+		prev := stdout
+		stdout = &buf
+		defer func() { stdout = prev }()
+		err = execSession(client, req.Cmd, req.Args)
+		// A dropped connection means Delve went away; reconnect on the next request.
+		if errors.Is(err, rpc.ErrShutdown) || errors.Is(err, io.EOF) {
+			d.close()
+		}
+		return err
+	})
+	resp := daemonResponse{Output: buf.String(), State: lastState}
+	if err != nil {
+		resp.Err = err.Error()
+	}
+	return resp
+}
+
+// withRequestEnv runs fn in the request's working directory and environment,
+// restoring the daemon's own afterwards. Relative paths such as
+// snapshot -dbg ./dir then resolve as they would in the client.
+func withRequestEnv(req daemonRequest, fn func() error) error {
+	if req.Dir != "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		if err := os.Chdir(req.Dir); err != nil {
+			return fmt.Errorf("chdir to client directory: %w", err)
+		}
+		defer os.Chdir(wd)
+	}
+	for k, v := range req.Env {
+		k := k
+		prev, had := os.LookupEnv(k)
+		if v != "" {
+			os.Setenv(k, v)
+		} else {
+			os.Unsetenv(k)
+		}
+		defer func() {
+			if had {
+				os.Setenv(k, prev)
+			} else {
+				os.Unsetenv(k)
+			}
+		}()
+	}
+	return fn()
+}
+
+func serveDaemon() error {
+	sock := getDaemonSockPath()
+	if err := os.MkdirAll(filepath.Dir(sock), 0755); err != nil {
+		return err
+	}
+	_ = os.Remove(sock) // left behind by a daemon that did not shut down cleanly
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", sock, err)
+	}
+	defer os.Remove(filepath.Join(filepath.Dir(sock), "daemon.pid"))
+	defer ln.Close()
+
+	d := &daemon{}
+	defer d.close()
+	fmt.Println("daemon listening on", sock)
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		var req daemonRequest
+		if err := json.NewDecoder(conn).Decode(&req); err != nil {
+			conn.Close()
+			continue
+		}
+		if req.Cmd == daemonShutdown {
+			_ = json.NewEncoder(conn).Encode(daemonResponse{Output: "daemon stopped\n"})
+			conn.Close()
+			fmt.Println("daemon stopped")
+			return nil
+		}
+		_ = json.NewEncoder(conn).Encode(d.exec(req))
+		conn.Close()
+	}
+}
+
+// callDaemon sends one request and returns the daemon's response.
+func callDaemon(sock string, req daemonRequest) (*daemonResponse, error) {
+	conn, err := net.DialTimeout("unix", sock, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("send to daemon: %w", err)
+	}
+	var resp daemonResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("read from daemon: %w", err)
+	}
+	return &resp, nil
+}
+
+// forwardToDaemon runs cmd through the daemon when one is listening. It
+// reports false when there is no daemon (or DLV_NO_DAEMON is set), in which
+// case the caller connects to Delve directly.
+func forwardToDaemon(cmd string, args []string) (bool, error) {
+	if v := strings.TrimSpace(os.Getenv("DLV_NO_DAEMON")); v != "" && v != "0" {
+		return false, nil
+	}
+	sock := getDaemonSockPath()
+	if _, err := os.Stat(sock); err != nil {
+		return false, nil
+	}
+	resp, err := callDaemon(sock, newDaemonRequest(cmd, args))
+	if err != nil {
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			fmt.Fprintf(os.Stderr, "daemon socket %s is stale; removing it and connecting directly\n", sock)
+			_ = os.Remove(sock)
+			return false, nil
+		}
+		return true, err
+	}
+	fmt.Print(resp.Output)
+	lastState = resp.State
+	if resp.Err != "" {
+		return true, errors.New(resp.Err)
+	}
+	return true, nil
+}
+
+// stopDaemon asks the daemon listening on sock to exit and reports whether one answered.
+func stopDaemon(sock string) bool {
+	if _, err := os.Stat(sock); err != nil {
+		return false
+	}
+	resp, err := callDaemon(sock, daemonRequest{Cmd: daemonShutdown})
+	if err != nil {
+		_ = os.Remove(sock)
+		return false
+	}
+	fmt.Print(resp.Output)
+	return true
+}
@@ -0,0 +1,147 @@
+// Display list: expressions saved in .dlv/display.json and evaluated after
+// every stop of continue, next, step, stepout, halt and wait (like gdb's display).
+package delvehelper
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-delve/delve/service/api"
+)
+
+const displayFile = "display.json"
+
+type displayExpr struct {
+	ID   int    `json:"id"`
+	Expr string `json:"expr"`
+}
+
+func loadDisplays() []displayExpr {
+	b, err := os.ReadFile(filepath.Join(getDlvDir(), displayFile))
+	if err != nil {
+		return nil
+	}
+	var list []displayExpr
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil
+	}
+	return list
+}
+
+func saveDisplays(list []displayExpr) error {
+	path := filepath.Join(getDlvDir(), displayFile)
+	if len(list) == 0 {
+		os.Remove(path)
+		return nil
+	}
+	b, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0644)
+}
+
+// printDisplays evaluates every display expression in the scope of state.
+// Evaluation errors are printed in place of the value, never returned.
+func printDisplays(client *loggingClient, state *api.DebuggerState, list []displayExpr) {
+	if state == nil || state.Exited || state.Running {
+		return
+	}
+	scope := scopeFromState(state)
+	cfg := api.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 200}
+	var values []api.Variable
+	for _, d := range list {
+		v, err := client.EvalVariable(scope, d.Expr, cfg)
+		switch {
+		case err != nil:
+			fmt.Fprintf(stdout, "%d: %s = <error: %v>\n", d.ID, d.Expr, err)
+		case v == nil:
+			fmt.Fprintf(stdout, "%d: %s = <nothing>\n", d.ID, d.Expr)
+		default:
+			fmt.Fprintf(stdout, "%d: %s = %s\n", d.ID, d.Expr, v.Value)
+			values = append(values, *v)
+		}
+	}
+	recordValues(state, "display", values)
+}
+
+// printStop prints where the target stopped followed by the display list.
+func printStop(client *loggingClient, state *api.DebuggerState) error {
+	if err := printState(state); err != nil {
+		return err
+	}
+	if !state.Exited && !state.Running {
+		recordStop(state)
+	}
+	printDisplays(client, state, loadDisplays())
+	return nil
+}
+
+// cmdDisplay adds an expression to the display list and shows it at the
+// current stop; without arguments it evaluates the whole list.
+func cmdDisplay(client *loggingClient, state *api.DebuggerState, args []string) error {
+	list := loadDisplays()
+	if len(args) == 0 {
+		if len(list) == 0 {
+			fmt.Fprintln(stdout, "no display expressions (add one with 'delve-helper display <expr>')")
+			return nil
+		}
+		if state.Running || state.Exited {
+			for _, d := range list {
+				fmt.Fprintf(stdout, "%d: %s\n", d.ID, d.Expr)
+			}
+			return nil
+		}
+		printDisplays(client, state, list)
+		return nil
+	}
+	d := displayExpr{ID: 1, Expr: strings.Join(args, " ")}
+	for _, e := range list {
+		if e.Expr == d.Expr {
+			return fmt.Errorf("%q is already display %d", d.Expr, e.ID)
+		}
+		if e.ID >= d.ID {
+			d.ID = e.ID + 1
+		}
+	}
+	if err := saveDisplays(append(list, d)); err != nil {
+		return err
+	}
+	printDisplays(client, state, []displayExpr{d})
+	return nil
+}
+
+// cmdUndisplay removes display expressions by number, or all of them with -all.
+func cmdUndisplay(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: undisplay <n>... | undisplay -all")
+	}
+	list := loadDisplays()
+	if len(args) == 1 && args[0] == "-all" {
+		fmt.Printf("removed %d display expression(s)\n", len(list))
+		return saveDisplays(nil)
+	}
+	for _, a := range args {
+		id, err := strconv.Atoi(a)
+		if err != nil {
+			return fmt.Errorf("invalid display number %q", a)
+		}
+		found := false
+		for i, d := range list {
+			if d.ID == id {
+				list = append(list[:i], list[i+1:]...)
+				found = true
+				fmt.Printf("removed display %d: %s\n", d.ID, d.Expr)
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("no display %d", id)
+		}
+	}
+	return saveDisplays(list)
+}
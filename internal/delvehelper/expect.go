@@ -0,0 +1,191 @@
+// Runtime assertions: expect compares an expression evaluated at the current
+// stop with a value, expect-loc checks where the target stopped. Both fail
+// with a got/want diff, which makes them the checks to use in script files.
+package delvehelper
+
+import (
+	"cmp"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-delve/delve/service/api"
+)
+
+// expectOps are the comparison operators of expect, longest first so that
+// splitting "a<=b" does not stop at "<".
+var expectOps = []string{"==", "!=", "<=", ">=", "<", ">", "contains"}
+
+// splitExpect finds the operator in args: preferably a word of its own
+// ("end" "==" "16"), otherwise inside a single word ("end==16").
+func splitExpect(args []string) (expr, op, want string, err error) {
+	for i := len(args) - 2; i > 0; i-- {
+		for _, o := range expectOps {
+			if args[i] == o {
+				return strings.Join(args[:i], " "), o, strings.Join(args[i+1:], " "), nil
+			}
+		}
+	}
+	joined := strings.Join(args, " ")
+	for _, o := range expectOps {
+		if o == "contains" {
+			continue
+		}
+		if i := strings.Index(joined, o); i > 0 {
+			expr, want = strings.TrimSpace(joined[:i]), strings.TrimSpace(joined[i+len(o):])
+			if expr != "" && want != "" {
+				return expr, o, want, nil
+			}
+		}
+	}
+	return "", "", "", fmt.Errorf("usage: expect <expr> <op> <value> (op: %s)", strings.Join(expectOps, " "))
+}
+
+// expectValue is how a variable is compared: the plain value for basic
+// kinds (strings unquoted), the one-line rendering for everything else.
+func expectValue(v *api.Variable) string {
+	switch v.Kind {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return v.Value
+	}
+	return v.SinglelineString()
+}
+
+// compareExpect applies op numerically when both sides are numbers and as
+// strings otherwise. A quoted want is unquoted first.
+func compareExpect(got, op, want string) (bool, error) {
+	if uq, err := strconv.Unquote(want); err == nil {
+		want = uq
+	}
+	if op == "contains" {
+		return strings.Contains(got, want), nil
+	}
+	var c int
+	gi, err1 := parseExpectInt(got)
+	wi, err2 := parseExpectInt(want)
+	gf, err3 := strconv.ParseFloat(got, 64)
+	wf, err4 := strconv.ParseFloat(want, 64)
+	switch {
+	case err1 == nil && err2 == nil:
+		c = cmp.Compare(gi, wi)
+	case err3 == nil && err4 == nil:
+		c = cmp.Compare(gf, wf)
+	default:
+		c = strings.Compare(got, want)
+	}
+	switch op {
+	case "==":
+		return c == 0, nil
+	case "!=":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	case ">=":
+		return c >= 0, nil
+	}
+	return false, fmt.Errorf("unknown operator %q", op)
+}
+
+// parseExpectInt parses s as a decimal integer, or as hex with a 0x prefix.
+// Leading zeros stay decimal: 016 is sixteen, as a reader would expect.
+func parseExpectInt(s string) (int64, error) {
+	sign, digits := "", s
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		sign, digits = digits[:1], digits[1:]
+	}
+	if hex, ok := strings.CutPrefix(digits, "0x"); ok {
+		return strconv.ParseInt(sign+hex, 16, 64)
+	}
+	if hex, ok := strings.CutPrefix(digits, "0X"); ok {
+		return strconv.ParseInt(sign+hex, 16, 64)
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// stoppedHere returns the location label used in expect messages, or an
+// error when there is no stopped goroutine to evaluate in.
+func stoppedHere(state *api.DebuggerState) (string, error) {
+	if state.Running {
+		return "", fmt.Errorf("process is running; stop it first (halt or wait)")
+	}
+	if state.Exited || state.SelectedGoroutine == nil {
+		return "", fmt.Errorf("no stopped goroutine to evaluate in")
+	}
+	loc, fn := stopLocation(state)
+	if fn != "" {
+		loc += " (" + fn + ")"
+	}
+	return loc, nil
+}
+
+// cmdExpect evaluates expr at the current stop and fails unless it
+// compares to the value with op.
+func cmdExpect(client *loggingClient, state *api.DebuggerState, args []string) error {
+	expr, op, want, err := splitExpect(args)
+	if err != nil {
+		return err
+	}
+	here, err := stoppedHere(state)
+	if err != nil {
+		return err
+	}
+	cfg := api.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 200, MaxArrayValues: 64, MaxStructFields: -1}
+	v, err := client.EvalVariable(scopeFromState(state), expr, cfg)
+	if err != nil {
+		return fmt.Errorf("expect %s: %w", expr, err)
+	}
+	if v == nil {
+		return fmt.Errorf("expect %s: expression evaluated to nothing", expr)
+	}
+	recordValues(state, "expect", []api.Variable{*v})
+	got := expectValue(v)
+	ok, err := compareExpect(got, op, want)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("expectation failed at %s\n  expr: %s\n  want: %s %s\n  got:  %s", here, expr, op, want, got)
+	}
+	fmt.Fprintf(stdout, "ok: %s %s %s (got %s)\n", expr, op, want, got)
+	return nil
+}
+
+// cmdExpectLoc fails unless the target is stopped at file:line. file may be
+// a base name or any trailing part of the path.
+func cmdExpectLoc(_ *loggingClient, state *api.DebuggerState, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: expect-loc <file:line>")
+	}
+	i := strings.LastIndex(args[0], ":")
+	if i <= 0 {
+		return fmt.Errorf("expect-loc: %q is not file:line", args[0])
+	}
+	wantFile := filepath.ToSlash(args[0][:i])
+	wantLine, err := strconv.Atoi(args[0][i+1:])
+	if err != nil {
+		return fmt.Errorf("expect-loc: %q is not file:line", args[0])
+	}
+	if _, err := stoppedHere(state); err != nil {
+		return err
+	}
+	cur := state.SelectedGoroutine.UserCurrentLoc
+	if cur.File == "" {
+		cur = state.SelectedGoroutine.CurrentLoc
+	}
+	file := filepath.ToSlash(localPath(cur.File))
+	fileOK := file == wantFile || strings.HasSuffix(file, "/"+strings.TrimPrefix(wantFile, "./"))
+	if !fileOK || cur.Line != wantLine {
+		return fmt.Errorf("expectation failed: stopped at the wrong location\n  want: %s\n  got:  %s:%d", args[0], file, cur.Line)
+	}
+	fmt.Fprintf(stdout, "ok: stopped at %s:%d\n", filepath.Base(file), cur.Line)
+	return nil
+}
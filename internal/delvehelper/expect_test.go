@@ -0,0 +1,64 @@
+package delvehelper
+
+import "testing"
+
+func TestSplitExpect(t *testing.T) {
+	for _, tc := range []struct {
+		args           []string
+		expr, op, want string
+		err            bool
+	}{
+		{args: []string{"end", "==", "16"}, expr: "end", op: "==", want: "16"},
+		{args: []string{"end==16"}, expr: "end", op: "==", want: "16"},
+		{args: []string{"len(s)", "<=", "3"}, expr: "len(s)", op: "<=", want: "3"},
+		{args: []string{"a<=b"}, expr: "a", op: "<=", want: "b"},
+		{args: []string{"x", "!=", "nil"}, expr: "x", op: "!=", want: "nil"},
+		{args: []string{"s", "contains", "needle", "hay"}, expr: "s", op: "contains", want: "needle hay"},
+		{args: []string{"a", "+", "b", ">", "c"}, expr: "a + b", op: ">", want: "c"},
+		{args: []string{"m[\"k\"]", "==", "\"v w\""}, expr: "m[\"k\"]", op: "==", want: "\"v w\""},
+		{args: []string{"end"}, err: true},
+		{args: []string{"==", "16"}, err: true},
+		{args: nil, err: true},
+	} {
+		expr, op, want, err := splitExpect(tc.args)
+		if tc.err {
+			if err == nil {
+				t.Errorf("splitExpect(%q) = %q %q %q, want an error", tc.args, expr, op, want)
+			}
+			continue
+		}
+		if err != nil || expr != tc.expr || op != tc.op || want != tc.want {
+			t.Errorf("splitExpect(%q) = %q %q %q, %v; want %q %q %q", tc.args, expr, op, want, err, tc.expr, tc.op, tc.want)
+		}
+	}
+}
+
+func TestCompareExpect(t *testing.T) {
+	for _, tc := range []struct {
+		got, op, want string
+		ok            bool
+	}{
+		{"16", "==", "16", true},
+		{"16", "==", "016", true}, // decimal, not octal
+		{"14", "==", "016", false},
+		{"255", "==", "0xff", true},
+		{"-16", "==", "-0x10", true},
+		{"9", "<", "10", true}, // numeric, not string order
+		{"10", ">=", "9", true},
+		{"1.5", ">", "1.25", true},
+		{"2", "==", "2.0", true},
+		{"abc", "<", "abd", true},
+		{"hello", "==", `"hello"`, true},
+		{"a b", "contains", `" b"`, true},
+		{"true", "!=", "false", true},
+		{"0x10", "==", "16", true},
+	} {
+		ok, err := compareExpect(tc.got, tc.op, tc.want)
+		if err != nil || ok != tc.ok {
+			t.Errorf("compareExpect(%q %s %q) = %v, %v; want %v", tc.got, tc.op, tc.want, ok, err, tc.ok)
+		}
+	}
+	if _, err := compareExpect("1", "=~", "1"); err == nil {
+		t.Error("compareExpect with an unknown operator: want an error")
+	}
+}
@@ -0,0 +1,197 @@
+// from-panic: turn a panic or GOTRACEBACK=all dump into breakpoints in the
+// local module and a seed for the Debugging Trace table.
+package delvehelper
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// findModule walks up from dir to the nearest go.mod and returns its
+// directory and module path; both are "" outside a module.
+func findModule(dir string) (root, modPath string) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", ""
+	}
+	for {
+		if b, err := os.ReadFile(filepath.Join(dir, "go.mod")); err == nil {
+			for _, line := range strings.Split(string(b), "\n") {
+				if f := strings.Fields(line); len(f) >= 2 && f[0] == "module" {
+					return dir, strings.Trim(f[1], `"`)
+				}
+			}
+			return dir, ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ""
+		}
+		dir = parent
+	}
+}
+
+// funcPackage returns the import path of a traceback function name, e.g.
+// github.com/a/b/sub for github.com/a/b/sub.(*T).Run.func1.
+func funcPackage(fn string) string {
+	slash := strings.LastIndex(fn, "/")
+	pkg, _, _ := strings.Cut(fn[slash+1:], ".")
+	return fn[:slash+1] + pkg
+}
+
+// moduleFrame maps f to a file in the module at root. It reports false for
+// frames outside the module (standard library, dependencies, runtime).
+func moduleFrame(f stackFrame, root, modPath string) (string, bool) {
+	if isRuntimeFrame(f) {
+		return "", false
+	}
+	pkg := funcPackage(f.Func)
+	switch {
+	case pkg == "main", modPath != "" && (pkg == modPath || strings.HasPrefix(pkg, modPath+"/")):
+	case modPath == "" && strings.Contains(strings.Split(pkg, "/")[0], "."):
+	default:
+		return "", false
+	}
+	if p := localPath(f.File); fileExists(p) {
+		return p, true
+	}
+	// Built elsewhere (container, CI): match the longest path suffix that
+	// exists under the module root, e.g. /app/pipeline.go -> root/pipeline.go.
+	parts := strings.Split(filepath.ToSlash(f.File), "/")
+	for i := 1; i < len(parts) && root != ""; i++ {
+		if p := filepath.Join(root, filepath.Join(parts[i:]...)); fileExists(p) {
+			return p, true
+		}
+	}
+	return f.File, false
+}
+
+func fileExists(path string) bool {
+	st, err := os.Stat(path)
+	return err == nil && !st.IsDir()
+}
+
+type panicSuggestion struct {
+	Loc    string // locspec for break
+	Func   string
+	Reason string
+}
+
+// panicSuggestions picks breakpoints from tb: every module frame of the
+// panicking goroutine (innermost first) and the goroutine's creation site.
+// For a dump without a panic, the innermost module frame of each goroutine.
+func panicSuggestions(tb traceback, root, modPath string) []panicSuggestion {
+	var out []panicSuggestion
+	seen := map[string]bool{}
+	add := func(f stackFrame, reason string) bool {
+		local, ok := moduleFrame(f, root, modPath)
+		if !ok {
+			return false
+		}
+		loc := fmt.Sprintf("%s:%d", filepath.Base(local), f.Line)
+		if !seen[loc] {
+			seen[loc] = true
+			out = append(out, panicSuggestion{Loc: loc, Func: f.Func, Reason: reason})
+		}
+		return true
+	}
+	for i, g := range tb.Goroutines {
+		if tb.Panic != "" && i > 0 {
+			break
+		}
+		first := true
+		for _, f := range g.Frames {
+			reason := fmt.Sprintf("caller in goroutine %d", g.ID)
+			if first {
+				reason = fmt.Sprintf("innermost module frame of goroutine %d", g.ID)
+				if tb.Panic != "" {
+					reason = "panic site: " + firstLine(tb.Panic)
+				}
+			}
+			if add(f, reason) {
+				if tb.Panic == "" {
+					break
+				}
+				first = false
+			}
+		}
+		if g.CreatedBy != nil && tb.Panic != "" {
+			add(*g.CreatedBy, fmt.Sprintf("goroutine %d is created here", g.ID))
+		}
+	}
+	return out
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return strings.TrimPrefix(line, "panic: ")
+}
+
+// cmdFromPanic parses a traceback from a file (or - for stdin) and prints the
+// goroutines, suggested break commands and report-trace-row commands.
+func cmdFromPanic(args []string) error {
+	fs := flag.NewFlagSet("from-panic", flag.ContinueOnError)
+	modDir := fs.String("C", ".", "directory inside the local module the trace belongs to")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: from-panic [-C DIR] <file|->")
+	}
+	var data []byte
+	var err error
+	if fs.Arg(0) == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(fs.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+	tb := parseTraceback(string(data))
+	if len(tb.Goroutines) == 0 {
+		return fmt.Errorf("no goroutine traceback found in %s", fs.Arg(0))
+	}
+	root, modPath := findModule(*modDir)
+
+	if tb.Panic != "" {
+		fmt.Println(tb.Panic)
+		fmt.Println()
+	}
+	for _, g := range tb.Goroutines {
+		fmt.Printf("goroutine %d [%s]:\n", g.ID, g.State)
+		for _, f := range g.Frames {
+			mark := " "
+			if _, ok := moduleFrame(f, root, modPath); ok {
+				mark = "*"
+			}
+			fmt.Println(strings.TrimRight(fmt.Sprintf(" %s %s %s:%d %s", mark, f.Func, f.File, f.Line, f.Offset), " "))
+		}
+		if g.CreatedBy != nil {
+			fmt.Printf("   created by %s %s:%d\n", g.CreatedBy.Func, g.CreatedBy.File, g.CreatedBy.Line)
+		}
+	}
+	fmt.Println("(* = frame in module", modPath+")")
+
+	sugg := panicSuggestions(tb, root, modPath)
+	if len(sugg) == 0 {
+		fmt.Println("\nno frames map to the local module; run from-panic inside it or pass -C DIR")
+		return nil
+	}
+	fmt.Println("\nSuggested breakpoints:")
+	for _, s := range sugg {
+		fmt.Printf("  delve-helper break %-20s # %s\n", s.Loc, s.Func)
+	}
+	dbg := os.Getenv("DBG_DIR")
+	n := nextTraceRow(dbg)
+	fmt.Println("\nTrace seed:")
+	for i, s := range sugg {
+		fmt.Printf("  delve-helper report-trace-row -n %d -action set -loc %q -reason %q \"$DBG_DIR\"\n",
+			n+i, s.Loc, s.Reason+" ("+s.Func+")")
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+// Value history: every stop and every print/locals/args/display result is
+// appended to .dlv/history.jsonl, numbered by stop, so diff-locals can show
+// what changed between two stops.
+package delvehelper
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-delve/delve/service/api"
+)
+
+const historyFile = "history.jsonl"
+
+type historyVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type historyEntry struct {
+	Stop int          `json:"stop"`
+	Time string       `json:"time"`
+	Kind string       `json:"kind"` // start | stop | print | locals | args | display | expect
+	Loc  string       `json:"loc,omitempty"`
+	Func string       `json:"func,omitempty"`
+	Vars []historyVar `json:"vars,omitempty"`
+}
+
+func readHistory(dlvDir string) []historyEntry {
+	f, err := os.Open(filepath.Join(dlvDir, historyFile))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var entries []historyEntry
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		var e historyEntry
+		if json.Unmarshal(sc.Bytes(), &e) == nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+func currentStop(dlvDir string) int {
+	entries := readHistory(dlvDir)
+	if len(entries) == 0 {
+		return 0
+	}
+	return entries[len(entries)-1].Stop
+}
+
+func appendHistory(dlvDir string, e historyEntry) {
+	e.Time = time.Now().Format(time.RFC3339)
+	b, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	_ = appendToFile(filepath.Join(dlvDir, historyFile), string(b)+"\n")
+}
+
+// stopLocation returns file:line and function of the selected goroutine.
+func stopLocation(state *api.DebuggerState) (string, string) {
+	if state == nil || state.SelectedGoroutine == nil {
+		return "", ""
+	}
+	loc := &state.SelectedGoroutine.UserCurrentLoc
+	if loc.File == "" {
+		loc = &state.SelectedGoroutine.CurrentLoc
+	}
+	fn := ""
+	if loc.Function != nil {
+		fn = loc.Function.Name()
+	}
+	return fmt.Sprintf("%s:%d", filepath.Base(loc.File), loc.Line), fn
+}
+
+// recordStart begins a new run in the history, so values read right after
+// start are not mixed into the previous session's last stop.
+func recordStart(dlvDir, target string) {
+	appendHistory(dlvDir, historyEntry{Stop: currentStop(dlvDir) + 1, Kind: "start", Loc: target})
+}
+
+// recordStop numbers a new stop of the target.
+func recordStop(state *api.DebuggerState) {
+	dir := getDlvDir()
+	loc, fn := stopLocation(state)
+	appendHistory(dir, historyEntry{Stop: currentStop(dir) + 1, Kind: "stop", Loc: loc, Func: fn})
+}
+
+// recordValues stores evaluated variables under the current stop.
+func recordValues(state *api.DebuggerState, kind string, vars []api.Variable) {
+	if len(vars) == 0 {
+		return
+	}
+	dir := getDlvDir()
+	loc, fn := stopLocation(state)
+	e := historyEntry{Stop: currentStop(dir), Kind: kind, Loc: loc, Func: fn}
+	for i := range vars {
+		e.Vars = append(e.Vars, historyVar{Name: vars[i].Name, Value: vars[i].SinglelineString()})
+	}
+	appendHistory(dir, e)
+}
+
+// stopValues is everything recorded at one stop; later reads of a name win.
+type stopValues struct {
+	Stop   int
+	Loc    string
+	Func   string
+	Names  []string // first-seen order
+	Values map[string]string
+}
+
+func valuesByStop(entries []historyEntry) map[int]*stopValues {
+	stops := map[int]*stopValues{}
+	for _, e := range entries {
+		if len(e.Vars) == 0 {
+			continue
+		}
+		sv := stops[e.Stop]
+		if sv == nil {
+			sv = &stopValues{Stop: e.Stop, Loc: e.Loc, Func: e.Func, Values: map[string]string{}}
+			stops[e.Stop] = sv
+		}
+		for _, v := range e.Vars {
+			if _, ok := sv.Values[v.Name]; !ok {
+				sv.Names = append(sv.Names, v.Name)
+			}
+			sv.Values[v.Name] = v.Value
+		}
+	}
+	return stops
+}
+
+// cmdDiffLocals compares the values recorded at two stops (default: the last
+// two stops with recorded values; one argument compares it with the last).
+func cmdDiffLocals(args []string) error {
+	fs := flag.NewFlagSet("diff-locals", flag.ContinueOnError)
+	list := fs.Bool("list", false, "list the stops that have recorded values")
+	all := fs.Bool("all", false, "also print unchanged values")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	stops := valuesByStop(readHistory(getDlvDir()))
+	var ids []int
+	for id := range stops {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	if *list {
+		if len(ids) == 0 {
+			fmt.Println("no recorded values (print, locals, args and display record them)")
+		}
+		for _, id := range ids {
+			sv := stops[id]
+			fmt.Printf("stop %d  %s %s  (%d values)\n", id, sv.Loc, sv.Func, len(sv.Names))
+		}
+		return nil
+	}
+
+	var a, b int
+	switch fs.NArg() {
+	case 0:
+		if len(ids) < 2 {
+			return fmt.Errorf("need values recorded at two stops; have %d (run locals at each stop)", len(ids))
+		}
+		a, b = ids[len(ids)-2], ids[len(ids)-1]
+	case 1, 2:
+		for i, s := range fs.Args() {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				return fmt.Errorf("invalid stop %q (see diff-locals -list)", s)
+			}
+			if i == 0 {
+				a = n
+			} else {
+				b = n
+			}
+		}
+		if fs.NArg() == 1 {
+			if len(ids) == 0 {
+				return fmt.Errorf("no recorded values")
+			}
+			b = ids[len(ids)-1]
+		}
+	default:
+		return fmt.Errorf("usage: diff-locals [-list] [-all] [stopA [stopB]]")
+	}
+	sa, sb := stops[a], stops[b]
+	if sa == nil || sb == nil {
+		return fmt.Errorf("no values recorded at stop %d or %d (see diff-locals -list)", a, b)
+	}
+
+	fmt.Printf("stop %d (%s %s) -> stop %d (%s %s)\n", a, sa.Loc, sa.Func, b, sb.Loc, sb.Func)
+	changed := 0
+	for _, name := range sb.Names {
+		old, ok := sa.Values[name]
+		switch {
+		case !ok:
+			fmt.Printf("+ %s = %s\n", name, sb.Values[name])
+			changed++
+		case old != sb.Values[name]:
+			fmt.Printf("~ %s: %s → %s\n", name, old, sb.Values[name])
+			changed++
+		case *all:
+			fmt.Printf("  %s = %s\n", name, old)
+		}
+	}
+	for _, name := range sa.Names {
+		if _, ok := sb.Values[name]; !ok {
+			fmt.Printf("- %s = %s\n", name, sa.Values[name])
+			changed++
+		}
+	}
+	if changed == 0 {
+		fmt.Println("no changes")
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+// Session liveness: detect sessions whose dlv process died or whose PID was
+// reused, and clean up the stale .dlv files.
+package delvehelper
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// processCmdline returns the command line of pid, or "" if the process does
+// not exist. It reads /proc where available and falls back to ps (macOS).
+func processCmdline(pid int) string {
+	if _, err := os.Stat("/proc/self/cmdline"); err == nil {
+		b, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "cmdline"))
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(strings.ReplaceAll(string(b), "\x00", " "))
+	}
+	out, err := exec.Command("ps", "-p", strconv.Itoa(pid), "-o", "command=").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// isDlvProcess reports whether pid is a live dlv process. A zombie or a PID
+// reused by an unrelated program reports false.
+func isDlvProcess(pid int) bool {
+	cmdline := processCmdline(pid)
+	if cmdline == "" {
+		return false
+	}
+	exe := strings.Fields(cmdline)[0]
+	return strings.HasPrefix(filepath.Base(exe), "dlv")
+}
+
+func readSessionPid(dlvDir string) (int, bool) {
+	pid, err := strconv.Atoi(readSessionFile(dlvDir, "pid"))
+	return pid, err == nil && pid > 0
+}
+
+// sessionProblem returns why the session in dlvDir is stale, or "" if its
+// recorded dlv process (if any) is still running.
+func sessionProblem(dlvDir string) string {
+	pid, ok := readSessionPid(dlvDir)
+	if !ok {
+		return ""
+	}
+	if cmdline := processCmdline(pid); cmdline == "" {
+		return fmt.Sprintf("dlv (pid %d) is no longer running", pid)
+	}
+	if !isDlvProcess(pid) {
+		return fmt.Sprintf("pid %d now belongs to another process, not dlv", pid)
+	}
+	return ""
+}
+
+// isUnreachable reports whether a dial failed because nothing listens at the
+// address (connection refused) or nothing answered in time.
+func isUnreachable(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, syscall.ECONNREFUSED) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// cleanStaleSession removes the session files in dlvDir and returns an error
+// that explains why, so the agent knows to start a new session.
+func cleanStaleSession(dlvDir, reason string) error {
+	for _, name := range []string{"addr", "pid", "target", "daemon.sock", "daemon.pid"} {
+		os.Remove(filepath.Join(dlvDir, name))
+	}
+	return fmt.Errorf("stale delve session in %s: %s; removed its addr/pid files — start a new session with 'delve-helper start'", dlvDir, reason)
+}
+
+// waitExit polls until pid is no longer a dlv process or timeout elapses.
+func waitExit(pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if !isDlvProcess(pid) {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// terminateDlv escalates from SIGTERM to SIGKILL, waiting up to timeout after
+// each signal. It never signals a PID that no longer belongs to dlv.
+func terminateDlv(pid int, timeout time.Duration) {
+	for _, sig := range []syscall.Signal{syscall.SIGTERM, syscall.SIGKILL} {
+		if !isDlvProcess(pid) {
+			return
+		}
+		if err := syscall.Kill(pid, sig); err != nil {
+			fmt.Printf("signal %v: %v (process may have already exited)\n", sig, err)
+			return
+		}
+		fmt.Printf("sent %v to delve (pid %d)\n", sig, pid)
+		if waitExit(pid, timeout) {
+			return
+		}
+	}
+	fmt.Printf("delve (pid %d) did not exit after SIGKILL\n", pid)
+}
@@ -0,0 +1,81 @@
+// Escaping for text the agent passes to the report-* commands: flag values
+// and Delve output can contain pipes, backticks, newlines and fence markers
+// that would end a table row, code span or code block early and break the
+// LaTeX rendered from it.
+package delvehelper
+
+import (
+	"strings"
+)
+
+// longestRun returns the length of the longest run of c in s.
+func longestRun(s string, c byte) int {
+	longest, n := 0, 0
+	for i := 0; i < len(s); i++ {
+		if s[i] != c {
+			n = 0
+			continue
+		}
+		n++
+		longest = max(longest, n)
+	}
+	return longest
+}
+
+// mdLine collapses s to a single line so it cannot start a new block.
+func mdLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// mdCell makes s safe as a pipe table cell: one line, every pipe escaped.
+// splitTableRow turns "\|" back into "|", inside code spans too, as GFM does.
+func mdCell(s string) string {
+	return strings.ReplaceAll(mdLine(s), "|", `\|`)
+}
+
+// mdCodeSpan returns s as an inline code span, delimited by a backtick run
+// longer than any inside it.
+func mdCodeSpan(s string) string {
+	s = mdLine(s)
+	if s == "" {
+		return ""
+	}
+	ticks := strings.Repeat("`", longestRun(s, '`')+1)
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		s = " " + s + " "
+	}
+	return ticks + s + ticks
+}
+
+// mdCodeBlock returns text as a fenced code block with the given info
+// string, using a fence longer than any backtick run in text.
+func mdCodeBlock(info, text string) string {
+	fence := strings.Repeat("`", max(3, longestRun(text, '`')+1))
+	return fence + info + "\n" + text + "\n" + fence + "\n"
+}
+
+// mdText returns free-form Markdown (root cause, fix, verification) with
+// any fence left open at the end closed, so it cannot swallow the sections
+// after it.
+func mdText(s string) string {
+	open := ""
+	for _, line := range strings.Split(s, "\n") {
+		m := mdFenceRE.FindStringSubmatch(line)
+		switch {
+		case open == "" && m != nil:
+			open = m[1]
+		case open != "" && closesFence(line, open):
+			open = ""
+		}
+	}
+	if open != "" {
+		s = strings.TrimRight(s, "\n") + "\n" + open
+	}
+	return s
+}
+
+// closesFence reports whether line closes a code block opened with fence.
+func closesFence(line, fence string) bool {
+	t := strings.TrimSpace(line)
+	return strings.HasPrefix(t, fence) && strings.Trim(t, fence[:1]) == ""
+}
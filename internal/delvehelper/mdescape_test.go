@@ -0,0 +1,181 @@
+package delvehelper
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// Hostile inputs: pipes, backticks, fences, newlines and \end{minted} lines
+// as they show up in Delve output and agent-written flags.
+const (
+	hostileLoc    = "pipe|line.go:30 `x`` y\n# z"
+	hostileReason = "a | b \\| c `d` and\nnew line ```"
+	hostileArgs   = "s = \"```\"\n```\n## Root Cause\n| a | b |\n| c | d |"
+	hostileLocals = "t = \"~~~\"\n~~~\n\\end{minted}\n  \\end{minted} again"
+	hostileStack  = "0 main.f()\n````\n1 main.main()"
+	hostileExpr   = "m[\"a`b|c\"]"
+	hostileVal    = "\"|`|\"\n```"
+	hostileDiff   = "-\tend := x\n+\tend := min(x, len(data)) // ```\n```"
+)
+
+func TestReportHostileInput(t *testing.T) {
+	for _, legacy := range []bool{false, true} {
+		dir := t.TempDir()
+		setup := []string{"report-init", "-pkg", "example", "-date", "2026-01-02", dir}
+		if legacy {
+			// An artifact dir from before report.json: the writers append.
+			if err := os.WriteFile(filepath.Join(dir, reportMainFile), []byte("# Debug Report — example — 2026-01-02\n\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			setup = nil
+		}
+		runCommands(t, [][]string{
+			setup,
+			{"report-hypothesis", "-loc", "f`g|h", "-expected", "x\n\n```go", "-actual", "y | z", dir},
+			{"report-trace-row", "-action", "set|hit", "-loc", hostileLoc, "-reason", hostileReason, dir},
+			{"report-trace-row", "-action", "hit", "-loc", "`", "-reason", "", dir},
+			{"report-annotate", "2", "-reason", "fired | end = 15 ``", dir},
+			{"report-evidence", "-loc", hostileLoc, "-args", hostileArgs, "-locals", hostileLocals,
+				"-stack", hostileStack, "-print-expr", hostileExpr, "-print-val", hostileVal, "-obs", "seen\n\n## Fix Applied", dir},
+			{"report-root-cause", "-text", "Off by one:\n\n```go\nend := x", dir},
+			{"report-fix", "-text", "Clamp `end` | done.", "-diff", hostileDiff, dir},
+			{"report-verification", "-text", "Tests pass ~~~", dir},
+		})
+		md, _, err := readReportMarkdown(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkHostileMarkdown(t, fixMarkdownTables(md), legacy)
+
+		problems, err := runReportCheck(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range problems {
+			if strings.Contains(p, "missing") {
+				t.Errorf("legacy=%v: report-check: %s", legacy, p)
+			}
+		}
+
+		// Through both LaTeX renderers: pandoc (the default when installed)
+		// goes through minted.lua, the native one through mdlatex.go.
+		for _, renderer := range []string{rendererNative, rendererPandoc} {
+			t.Run(fmt.Sprintf("legacy=%v/%s", legacy, renderer), func(t *testing.T) {
+				if renderer == rendererPandoc {
+					if _, err := exec.LookPath("pandoc"); err != nil {
+						t.Skip("pandoc not installed")
+					}
+				}
+				tex, _, err := MDToTex(dir, "example", "2026-01-02", renderer)
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkHostileLatex(t, tex, renderer)
+			})
+		}
+	}
+}
+
+func checkHostileMarkdown(t *testing.T, md string, legacy bool) {
+	t.Helper()
+	var sections, codes []string
+	var tables, hypotheses []mdBlock
+	wantHeading, wantHypothesis := "E1 — "+mdLine(hostileLoc), "Hypotheses"
+	if legacy {
+		wantHeading, wantHypothesis = mdLine(hostileLoc), "Hypothesis"
+	}
+	for _, b := range parseMarkdown(md) {
+		switch {
+		case b.Kind == mdHeading && b.Level <= 2:
+			sections = append(sections, b.Text)
+		case b.Kind == mdHeading:
+			if b.Text != wantHeading {
+				t.Errorf("evidence heading = %q", b.Text)
+			}
+		case b.Kind == mdTable && b.Header[0] == "ID":
+			hypotheses = append(hypotheses, b)
+		case b.Kind == mdTable:
+			tables = append(tables, b)
+		case b.Kind == mdCode:
+			codes = append(codes, b.Text)
+		}
+	}
+	wantSections := []string{
+		"Debug Report — example — 2026-01-02", wantHypothesis, "Debugging Trace",
+		"Breakpoints & Evidence", "Root Cause", "Fix Applied", "Post-fix Verification",
+	}
+	if strings.Join(sections, "\n") != strings.Join(wantSections, "\n") {
+		t.Errorf("sections = %q, want %q", sections, wantSections)
+	}
+
+	if !legacy {
+		if len(hypotheses) != 1 || len(hypotheses[0].Rows) != 1 || len(hypotheses[0].Rows[0]) != 5 {
+			t.Fatalf("want one hypotheses table with 1 row of 5 cells:\n%s", md)
+		}
+		row := hypotheses[0].Rows[0]
+		if in := parseInline(row[1]); len(in) != 1 || in[0].Text != "f`g|h" || row[3] != "y | z" {
+			t.Errorf("hypothesis row = %q", row)
+		}
+	}
+	if len(tables) != 1 || len(tables[0].Rows) != 2 {
+		t.Fatalf("want one trace table with 2 rows, got %d tables:\n%s", len(tables), md)
+	}
+	row := tables[0].Rows[0]
+	if len(row) != 4 || row[1] != "set|hit" {
+		t.Fatalf("row 1 = %q", row)
+	}
+	if in := parseInline(row[2]); len(in) != 1 || in[0].Kind != "code" || in[0].Text != mdLine(hostileLoc) {
+		t.Errorf("row 1 location = %q, parsed as %+v", row[2], in)
+	}
+	if in := parseInline(row[3]); len(in) != 3 || in[0].Text != "a | b | c " || in[2].Text != " and new line ```" {
+		t.Errorf("row 1 reasoning = %q, parsed as %+v", row[3], in)
+	}
+	if row := tables[0].Rows[1]; len(row) != 4 || row[3] != "fired | end = 15 ``" {
+		t.Errorf("row 2 = %q", row)
+	}
+
+	// Evidence outputs and the diff survive byte for byte.
+	for _, want := range []string{hostileArgs, hostileLocals, hostileStack, hostileVal, hostileDiff} {
+		found := false
+		for _, c := range codes {
+			found = found || c == want
+		}
+		if !found {
+			t.Errorf("code block %q not found in %q", want, codes)
+		}
+	}
+}
+
+var mintedEndRE = regexp.MustCompile(`(?m)^\s*\\end\{minted\}`)
+
+func checkHostileLatex(t *testing.T, tex, renderer string) {
+	t.Helper()
+	// Every minted environment must end at its own \end{minted}.
+	if b, e := strings.Count(tex, `\begin{minted}`), len(mintedEndRE.FindAllString(tex, -1)); b != e {
+		t.Errorf("%d minted environments but %d \\end{minted} lines", b, e)
+	}
+	if !strings.Contains(tex, `escapeinside=@@`) || !strings.Contains(tex, "\n  @\\textbackslash{}@end{minted} again\n") {
+		t.Error("\\end{minted} line in a code block not escaped")
+	}
+	for env, n := range map[string]int{"rootcausebox": 1, "fixbox": 1} {
+		if got := strings.Count(tex, `\begin{`+env+`}`); got != n {
+			t.Errorf("%d %s environments, want %d", got, env, n)
+		}
+	}
+	if renderer != rendererNative {
+		return // pandoc lays out tables and inline code its own way
+	}
+	start := strings.Index(tex, `\subsection{Debugging Trace}`)
+	table := tex[start+strings.Index(tex[start:], `\begin{longtable}`) : start+strings.Index(tex[start:], `\end{longtable}`)]
+	if rows := strings.Count(table, `\\`+"\n"); rows != 3 {
+		t.Errorf("trace longtable has %d rows, want 3 (header + 2):\n%s", rows, table)
+	}
+	if !strings.Contains(table, `set|hit & \texttt{pipe|line.go:30 `+"`x`` y \\# z}") {
+		t.Errorf("trace row 1 not rendered as expected:\n%s", table)
+	}
+}
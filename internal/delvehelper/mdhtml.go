@@ -0,0 +1,358 @@
+// HTML report: renders the same fragments as MDToTex into one self-contained
+// file (inline CSS, no scripts) with highlighted source blocks, the root
+// cause and fix boxes of styles.tex, a collapsible trace table and a TOC.
+package delvehelper
+
+import (
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// htmlInline renders inline Markdown as HTML.
+func htmlInline(s string) string {
+	var sb strings.Builder
+	for _, in := range parseInline(s) {
+		switch in.Kind {
+		case "text":
+			sb.WriteString(html.EscapeString(in.Text))
+		case "code":
+			sb.WriteString("<code>" + html.EscapeString(in.Text) + "</code>")
+		case "strong":
+			sb.WriteString("<strong>" + htmlInline(in.Text) + "</strong>")
+		case "emph":
+			sb.WriteString("<em>" + htmlInline(in.Text) + "</em>")
+		case "link":
+			if !safeHref(in.URL) {
+				sb.WriteString(htmlInline(in.Text))
+				continue
+			}
+			sb.WriteString(`<a href="` + html.EscapeString(in.URL) + `">` + htmlInline(in.Text) + "</a>")
+		case "span":
+			sb.WriteString(`<span class="` + html.EscapeString(in.Class) + `">` + htmlInline(in.Text) + "</span>")
+		case "break":
+			sb.WriteString("<br>\n")
+		}
+	}
+	return sb.String()
+}
+
+// safeHref reports whether u may become a link: a fragment, a relative URL
+// or http(s). Anything else (javascript:, data:, ...) is rendered as text.
+func safeHref(u string) bool {
+	i := strings.IndexAny(u, ":/?#")
+	if i < 0 || u[i] != ':' {
+		return true
+	}
+	scheme := strings.ToLower(u[:i])
+	return scheme == "http" || scheme == "https"
+}
+
+// goBuiltins are the predeclared identifiers minted colors like keywords.
+var goBuiltins = map[string]bool{
+	"append": true, "cap": true, "clear": true, "close": true, "complex": true, "copy": true,
+	"delete": true, "imag": true, "len": true, "make": true, "max": true, "min": true,
+	"new": true, "panic": true, "print": true, "println": true, "real": true, "recover": true,
+	"nil": true, "true": true, "false": true, "iota": true,
+	"bool": true, "byte": true, "error": true, "float32": true, "float64": true, "int": true,
+	"int8": true, "int16": true, "int32": true, "int64": true, "rune": true, "string": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true, "uintptr": true, "any": true,
+}
+
+// highlightGo returns src as escaped HTML with <span class> tokens. Spans
+// never cross a newline, so the result can be split into lines.
+func highlightGo(src string) string {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	var s scanner.Scanner
+	s.Init(file, []byte(src), func(token.Position, string) {}, scanner.ScanComments)
+
+	var sb strings.Builder
+	span := func(class, text string) {
+		if class == "" {
+			sb.WriteString(html.EscapeString(text))
+			return
+		}
+		for i, part := range strings.Split(text, "\n") {
+			if i > 0 {
+				sb.WriteString("\n")
+			}
+			if part != "" {
+				sb.WriteString(`<span class="` + class + `">` + html.EscapeString(part) + "</span>")
+			}
+		}
+	}
+	last := 0
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.SEMICOLON && lit == "\n" {
+			continue
+		}
+		off := file.Offset(pos)
+		if off < last || off > len(src) {
+			continue
+		}
+		text := lit
+		if text == "" {
+			text = tok.String()
+		}
+		if tok == token.COMMENT || tok == token.STRING {
+			// lit has \r removed; take the original bytes.
+			if end := off + len(lit); end <= len(src) && !strings.Contains(src[off:end], "\r") {
+				text = src[off:end]
+			}
+		}
+		if off+len(text) > len(src) || src[off:off+len(text)] != text {
+			continue
+		}
+		span("", src[last:off])
+		class := ""
+		switch {
+		case tok.IsKeyword():
+			class = "kw"
+		case tok == token.IDENT && goBuiltins[lit]:
+			class = "bi"
+		case tok == token.STRING || tok == token.CHAR:
+			class = "str"
+		case tok == token.INT || tok == token.FLOAT || tok == token.IMAG:
+			class = "num"
+		case tok == token.COMMENT:
+			class = "com"
+		}
+		span(class, text)
+		last = off + len(text)
+	}
+	span("", src[last:])
+	return sb.String()
+}
+
+// parseLineSet parses minted's highlightlines syntax ("30", "28-30,32").
+func parseLineSet(spec string) map[int]bool {
+	set := map[int]bool{}
+	for _, part := range strings.Split(spec, ",") {
+		lo, hi, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		a, err := strconv.Atoi(lo)
+		if err != nil {
+			continue
+		}
+		b := a
+		if isRange {
+			if b, err = strconv.Atoi(hi); err != nil {
+				continue
+			}
+		}
+		for n := a; n <= b; n++ {
+			set[n] = true
+		}
+	}
+	return set
+}
+
+// gobble removes the indentation common to all non-blank lines, like
+// minted's autogobble.
+func gobble(lines []string) []string {
+	prefix := ""
+	first := true
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		indent := l[:len(l)-len(strings.TrimLeft(l, " \t"))]
+		if first {
+			prefix, first = indent, false
+			continue
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		out[i] = strings.TrimPrefix(l, prefix)
+	}
+	return out
+}
+
+// htmlCode renders a fenced code block. Go blocks get line numbers (as
+// minted's linenos for go), firstnumber and highlightlines are honored.
+func htmlCode(b mdBlock) string {
+	lines := gobble(strings.Split(b.Text, "\n"))
+	var rendered []string
+	switch b.Lang {
+	case "go":
+		rendered = strings.Split(highlightGo(strings.Join(lines, "\n")), "\n")
+	default:
+		for _, l := range lines {
+			rendered = append(rendered, html.EscapeString(l))
+		}
+	}
+	first := 1
+	var hl map[int]bool
+	for _, kv := range b.Attrs {
+		switch kv[0] {
+		case "firstnumber":
+			if n, err := strconv.Atoi(kv[1]); err == nil {
+				first = n
+			}
+		case "highlightlines":
+			hl = parseLineSet(kv[1])
+		}
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<pre class="code lang-%s"><code>`, html.EscapeString(b.Lang))
+	for i, l := range rendered {
+		n := first + i
+		class := "line"
+		switch {
+		case hl[n]:
+			class += " hl"
+		case b.Lang == "diff" && strings.HasPrefix(lines[i], "+"):
+			class += " add"
+		case b.Lang == "diff" && strings.HasPrefix(lines[i], "-"):
+			class += " del"
+		}
+		sb.WriteString(`<span class="` + class + `">`)
+		if b.Lang == "go" {
+			fmt.Fprintf(&sb, `<span class="ln">%d</span>`, n)
+		}
+		sb.WriteString(l + "</span>\n")
+	}
+	sb.WriteString("</code></pre>\n")
+	return sb.String()
+}
+
+func htmlTable(b mdBlock) string {
+	var sb strings.Builder
+	sb.WriteString("<table>\n<thead><tr>")
+	for _, c := range b.Header {
+		sb.WriteString("<th>" + htmlInline(c) + "</th>")
+	}
+	sb.WriteString("</tr></thead>\n<tbody>\n")
+	for _, r := range b.Rows {
+		sb.WriteString("<tr>")
+		for i := range b.Header {
+			cell := ""
+			if i < len(r) {
+				cell = r[i]
+			}
+			sb.WriteString("<td>" + htmlInline(cell) + "</td>")
+		}
+		sb.WriteString("</tr>\n")
+	}
+	sb.WriteString("</tbody>\n</table>\n")
+	return sb.String()
+}
+
+// htmlBoxes maps section titles to the styles.tex box they are shown in.
+var htmlBoxes = map[string][2]string{
+	"Root Cause":  {"rootcause", "⚠ Root Cause"},
+	"Fix":         {"fix", "✔ Fix Applied"},
+	"Fix Applied": {"fix", "✔ Fix Applied"},
+}
+
+// renderHTML converts Markdown to an HTML body and its table of contents.
+// A Root Cause or Fix section becomes a box that runs to the next heading
+// of level 1 or 2, like wrapStyledSections.
+func renderHTML(md string) (body, toc string) {
+	var sb, tb strings.Builder
+	seen := map[string]int{}
+	inBox := false
+	section := ""
+	tb.WriteString("<ul>\n")
+	for _, b := range parseMarkdown(md) {
+		switch b.Kind {
+		case mdHeading:
+			id := headingID(b, seen)
+			title := htmlInline(b.Text)
+			if inBox && b.Level <= 2 {
+				sb.WriteString("</div></div>\n")
+				inBox = false
+			}
+			if b.Level > 1 && b.Level <= 4 {
+				fmt.Fprintf(&tb, `<li class="l%d"><a href="#%s">%s</a></li>`+"\n", b.Level, id, title)
+			}
+			if b.Level <= 2 {
+				section = b.Text
+			}
+			if box, ok := htmlBoxes[strings.TrimSpace(b.Text)]; ok && b.Level <= 2 {
+				fmt.Fprintf(&sb, "<div class=\"box %s\" id=\"%s\"><div class=\"box-title\">%s</div><div class=\"box-body\">\n", box[0], id, box[1])
+				inBox = true
+				continue
+			}
+			// Fragments use # for the report title and ## for sections; shift
+			// down one level below the page header.
+			lvl := b.Level + 1
+			if lvl > 6 {
+				lvl = 6
+			}
+			fmt.Fprintf(&sb, "<h%d id=\"%s\">%s</h%d>\n", lvl, id, title, lvl)
+		case mdParagraph:
+			sb.WriteString("<p>" + htmlInline(b.Text) + "</p>\n")
+		case mdCode:
+			sb.WriteString(htmlCode(b))
+		case mdTable:
+			if section == "Debugging Trace" {
+				fmt.Fprintf(&sb, "<details class=\"trace\" open>\n<summary>%d trace rows</summary>\n%s</details>\n", len(b.Rows), htmlTable(b))
+				continue
+			}
+			sb.WriteString(htmlTable(b))
+		case mdList:
+			tag := "ul"
+			if b.Ordered {
+				tag = "ol"
+			}
+			sb.WriteString("<" + tag + ">\n")
+			for _, it := range b.Items {
+				sb.WriteString("<li>" + htmlInline(it) + "</li>\n")
+			}
+			sb.WriteString("</" + tag + ">\n")
+		case mdQuote:
+			sb.WriteString("<blockquote><p>" + htmlInline(b.Text) + "</p></blockquote>\n")
+		case mdRule:
+			sb.WriteString("<hr>\n")
+		}
+	}
+	if inBox {
+		sb.WriteString("</div></div>\n")
+	}
+	tb.WriteString("</ul>")
+	return sb.String(), tb.String()
+}
+
+// MDToHTML reads the .md fragments of dbgDir and returns a self-contained
+// HTML document. pkg and date fill the header like in MDToTex.
+func MDToHTML(dbgDir, pkg, date string) (string, int, error) {
+	md, mdCount, err := readReportMarkdown(dbgDir)
+	if err != nil {
+		return "", 0, err
+	}
+	body, toc := renderHTML(fixMarkdownTables(md))
+	tpl, err := templateFS.ReadFile("templates/html/debug_report.html")
+	if err != nil {
+		return "", 0, fmt.Errorf("read template: %w", err)
+	}
+	var subtitle []string
+	if pkg != "" {
+		subtitle = append(subtitle, "<code>"+html.EscapeString(pkg)+"</code>")
+	}
+	if date != "" {
+		subtitle = append(subtitle, html.EscapeString(date))
+	}
+	title := "Debug Report"
+	if pkg != "" {
+		title += " — " + pkg
+	}
+	out := strings.NewReplacer(
+		"%%TITLE%%", html.EscapeString(title),
+		"%%SUBTITLE%%", strings.Join(subtitle, " — "),
+		"%%TOC%%", toc,
+		"%%BODY%%", body,
+	).Replace(string(tpl))
+	return out, mdCount, nil
+}
@@ -0,0 +1,54 @@
+package delvehelper
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderHTML(t *testing.T) {
+	md := "## Debugging Trace\n\n" +
+		"| # | Action | Location | Reasoning |\n" +
+		"| - | ------ | -------- | --------- |\n" +
+		"| 1 | set | `a<b>.go:3` | why |\n\n" +
+		"```go {highlightlines=11 firstnumber=10}\n" +
+		"\tif n > 0 { // check\n" +
+		"\t\treturn \"x\"\n" +
+		"\t}\n" +
+		"```\n\n" +
+		"## Root Cause\n\nOff by one.\n\n### Detail\n\nStill in the box.\n\n## Fix Applied\n\nClamp.\n\n" +
+		"## Post-fix Verification\n\nOK.\n"
+	body, toc := renderHTML(md)
+	for _, want := range []string{
+		"<details class=\"trace\" open>\n<summary>1 trace rows</summary>",
+		"<td><code>a&lt;b&gt;.go:3</code></td>",
+		`<span class="line"><span class="ln">10</span><span class="kw">if</span> n &gt; <span class="num">0</span> { <span class="com">// check</span></span>`,
+		`<span class="line hl"><span class="ln">11</span>	<span class="kw">return</span> <span class="str">&#34;x&#34;</span></span>`,
+		"<div class=\"box rootcause\" id=\"root-cause\"><div class=\"box-title\">⚠ Root Cause</div><div class=\"box-body\">\n<p>Off by one.</p>\n<h4 id=\"detail\">Detail</h4>\n<p>Still in the box.</p>\n</div></div>",
+		"<div class=\"box fix\" id=\"fix-applied\">",
+		"</div></div>\n<h3 id=\"post-fix-verification\">",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %q in:\n%s", want, body)
+		}
+	}
+	if !strings.Contains(toc, `<li class="l2"><a href="#root-cause">Root Cause</a></li>`) {
+		t.Errorf("toc missing Root Cause:\n%s", toc)
+	}
+}
+
+func TestHTMLInlineLinks(t *testing.T) {
+	for md, want := range map[string]string{
+		"[e1](#e1)":                        `<a href="#e1">e1</a>`,
+		"[log](dlv.log)":                   `<a href="dlv.log">log</a>`,
+		"[docs](https://go.dev/doc?a=1&b)": `<a href="https://go.dev/doc?a=1&amp;b">docs</a>`,
+		"[x](HTTP://example.com)":          `<a href="HTTP://example.com">x</a>`,
+		"[x](javascript:alert%281%29)":     "x",
+		"[x](JavaScript:void%200)":         "x",
+		"[x](data:text/html,hi)":           "x",
+		"[x](vbscript:msgbox)":             "x",
+	} {
+		if got := htmlInline(md); got != want {
+			t.Errorf("htmlInline(%q) = %q, want %q", md, got, want)
+		}
+	}
+}
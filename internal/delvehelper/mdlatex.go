@@ -0,0 +1,232 @@
+// Native Markdown → LaTeX: renders the blocks from parseMarkdown the way
+// pandoc plus minted.lua and fixLongtable do, so report-build can produce
+// debug_report.tex without pandoc.
+package delvehelper
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// latexEscaper escapes the characters that are special in LaTeX text mode.
+var latexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`$`, `\$`,
+	`&`, `\&`,
+	`#`, `\#`,
+	`_`, `\_`,
+	`%`, `\%`,
+	`^`, `\^{}`,
+	`~`, `\textasciitilde{}`,
+)
+
+func latexEscape(s string) string { return latexEscaper.Replace(s) }
+
+// longtableSpec returns the column spec used for report tables: fixed
+// proportions for the 2-, 3- and 4-column tables the report-* commands
+// write, equal widths otherwise.
+func longtableSpec(ncols int) string {
+	switch ncols {
+	case 2:
+		return `@{}p{0.38\linewidth}p{0.52\linewidth}@{}`
+	case 3:
+		return `@{}p{0.22\linewidth}p{0.28\linewidth}p{0.40\linewidth}@{}`
+	case 4:
+		return `@{}p{1.5cm}p{2.2cm}p{3.8cm}p{0.35\linewidth}@{}`
+	case 5:
+		return `@{}p{0.06\linewidth}p{0.2\linewidth}p{0.2\linewidth}p{0.2\linewidth}p{0.22\linewidth}@{}`
+	}
+	w := 0.88 / float64(ncols)
+	var sb strings.Builder
+	sb.WriteString("@{}")
+	for i := 0; i < ncols; i++ {
+		fmt.Fprintf(&sb, `p{%.2f\linewidth}`, w)
+	}
+	sb.WriteString("@{}")
+	return sb.String()
+}
+
+// statusBadge returns the styles.tex palette (ok, bad, open) of a
+// [status]{.badge .confirmed} span, or false when the span is no badge.
+func statusBadge(class string) (string, bool) {
+	classes := strings.Fields(class)
+	if !slices.Contains(classes, "badge") {
+		return "", false
+	}
+	switch {
+	case slices.Contains(classes, hypConfirmed):
+		return "ok", true
+	case slices.Contains(classes, hypRefuted):
+		return "bad", true
+	}
+	return "open", true
+}
+
+// latexInline renders inline Markdown as LaTeX.
+func latexInline(s string) string {
+	var sb strings.Builder
+	for _, in := range parseInline(s) {
+		switch in.Kind {
+		case "text":
+			sb.WriteString(latexEscape(in.Text))
+		case "code":
+			sb.WriteString(`\texttt{` + latexEscape(in.Text) + `}`)
+		case "strong":
+			sb.WriteString(`\textbf{` + latexInline(in.Text) + `}`)
+		case "emph":
+			sb.WriteString(`\emph{` + latexInline(in.Text) + `}`)
+		case "link":
+			if id, ok := strings.CutPrefix(in.URL, "#"); ok {
+				sb.WriteString(`\hyperref[` + id + `]{` + latexInline(in.Text) + `}`)
+				continue
+			}
+			url := strings.NewReplacer(`\`, `\\`, `#`, `\#`, `%`, `\%`, `{`, `\{`, `}`, `\}`).Replace(in.URL)
+			sb.WriteString(`\href{` + url + `}{` + latexInline(in.Text) + `}`)
+		case "span":
+			if palette, ok := statusBadge(in.Class); ok {
+				sb.WriteString(`\statusbadge{` + palette + `}{` + latexInline(in.Text) + `}`)
+				continue
+			}
+			sb.WriteString(latexInline(in.Text))
+		case "break":
+			sb.WriteString("\\\\\n")
+		}
+	}
+	return sb.String()
+}
+
+// headingID returns the heading's {#id} or builds a pandoc-style identifier
+// ("Root Cause" → "root-cause"), numbered when it repeats.
+func headingID(b mdBlock, seen map[string]int) string {
+	if b.ID != "" {
+		seen[b.ID]++
+		return b.ID
+	}
+	var sb strings.Builder
+	for _, in := range parseInline(b.Text) {
+		sb.WriteString(in.Text)
+	}
+	var id strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(sb.String()) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '.':
+			if dash && id.Len() > 0 {
+				id.WriteByte('-')
+			}
+			id.WriteRune(r)
+			dash = false
+		case r == ' ' || r == '-' || r == '\t':
+			dash = true
+		}
+	}
+	s := id.String()
+	if s == "" {
+		s = "section"
+	}
+	n := seen[s]
+	seen[s]++
+	if n > 0 {
+		s = fmt.Sprintf("%s-%d", s, n)
+	}
+	return s
+}
+
+var latexSections = []string{"section", "subsection", "subsubsection", "paragraph", "subparagraph", "subparagraph"}
+
+// mintedOptions mirrors minted.lua: autogobble plus the highlightlines,
+// firstnumber and highlightcolor attributes of the fence.
+func mintedOptions(b mdBlock) string {
+	opts := []string{"autogobble"}
+	for _, kv := range b.Attrs {
+		switch kv[0] {
+		case "highlightlines":
+			opts = append(opts, fmt.Sprintf("highlightlines={%s}", kv[1]))
+		case "firstnumber", "highlightcolor":
+			opts = append(opts, kv[0]+"="+kv[1])
+		}
+	}
+	return strings.Join(opts, ",")
+}
+
+// mintedEscapes are candidate escapeinside characters for mintedBody.
+const mintedEscapes = "@!|^~`"
+
+// mintedBody returns the block's minted options and content. A line
+// starting with \end{minted} would end the environment early, so its
+// backslash is typeset through minted's escapeinside with a character the
+// content does not use.
+func mintedBody(b mdBlock) (opts, text string) {
+	opts, text = mintedOptions(b), b.Text
+	lines := strings.Split(text, "\n")
+	esc := strings.IndexFunc(mintedEscapes, func(r rune) bool { return !strings.ContainsRune(text, r) })
+	for i, l := range lines {
+		rest := strings.TrimLeft(l, " \t")
+		if !strings.HasPrefix(rest, `\end{minted}`) {
+			continue
+		}
+		indent := l[:len(l)-len(rest)]
+		if esc < 0 {
+			// No free escape character: a space keeps the line from
+			// matching, at the cost of one character.
+			lines[i] = indent + `\ ` + rest[1:]
+			continue
+		}
+		c := mintedEscapes[esc : esc+1]
+		lines[i] = indent + c + `\textbackslash{}` + c + rest[1:]
+		if !strings.Contains(opts, "escapeinside") {
+			opts += ",escapeinside=" + c + c
+		}
+	}
+	return opts, strings.Join(lines, "\n")
+}
+
+// renderLatex converts Markdown to a LaTeX body (no preamble).
+func renderLatex(md string) []byte {
+	var sb strings.Builder
+	seen := map[string]int{}
+	for _, b := range parseMarkdown(md) {
+		switch b.Kind {
+		case mdHeading:
+			fmt.Fprintf(&sb, "\\%s{%s}\\label{%s}\n\n", latexSections[b.Level-1], latexInline(b.Text), headingID(b, seen))
+		case mdParagraph:
+			sb.WriteString(latexInline(b.Text))
+			sb.WriteString("\n\n")
+		case mdCode:
+			opts, text := mintedBody(b)
+			fmt.Fprintf(&sb, "\\begin{minted}[%s]{%s}\n%s\n\\end{minted}\n\n", opts, b.Lang, text)
+		case mdTable:
+			n := len(b.Header)
+			cells := func(row []string) string {
+				out := make([]string, n)
+				for i := 0; i < n && i < len(row); i++ {
+					out[i] = latexInline(row[i])
+				}
+				return strings.Join(out, " & ") + ` \\`
+			}
+			fmt.Fprintf(&sb, "\\begin{longtable}{%s}\n\\toprule\n%s\n\\midrule\n\\endhead\n", longtableSpec(n), cells(b.Header))
+			for _, r := range b.Rows {
+				sb.WriteString(cells(r) + "\n")
+			}
+			sb.WriteString("\\bottomrule\n\\end{longtable}\n\n")
+		case mdList:
+			env := "itemize"
+			if b.Ordered {
+				env = "enumerate"
+			}
+			fmt.Fprintf(&sb, "\\begin{%s}\n\\tightlist\n", env)
+			for _, it := range b.Items {
+				sb.WriteString(`\item` + "\n  " + latexInline(it) + "\n")
+			}
+			fmt.Fprintf(&sb, "\\end{%s}\n\n", env)
+		case mdQuote:
+			fmt.Fprintf(&sb, "\\begin{quote}\n%s\n\\end{quote}\n\n", latexInline(b.Text))
+		case mdRule:
+			sb.WriteString("\\begin{center}\\rule{0.5\\linewidth}{0.5pt}\\end{center}\n\n")
+		}
+	}
+	return []byte(sb.String())
+}
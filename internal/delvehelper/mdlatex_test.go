@@ -0,0 +1,74 @@
+package delvehelper
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderLatex(t *testing.T) {
+	md := "## Debugging Trace\n\n" +
+		"| # | Action | Location | Reasoning |\n" +
+		"| - | ------ | -------- | --------- |\n" +
+		"| 1 | set | `pipeline.go:30` | check end & 100% \\| more |\n\n" +
+		"**Source context:**\n\n" +
+		"```go {highlightlines=30 firstnumber=28 highlightcolor=yellow!40}\n" +
+		"\tend = len(data) - 1\n" +
+		"```\n\n" +
+		"Uses `` a`b `` and *x_y*.\n\n" +
+		"- first\n- second\n"
+	got := string(renderLatex(md))
+	for _, want := range []string{
+		`\subsection{Debugging Trace}\label{debugging-trace}`,
+		`\begin{longtable}{@{}p{1.5cm}p{2.2cm}p{3.8cm}p{0.35\linewidth}@{}}`,
+		`\# & Action & Location & Reasoning \\`,
+		`1 & set & \texttt{pipeline.go:30} & check end \& 100\% | more \\`,
+		`\textbf{Source context:}`,
+		"\\begin{minted}[autogobble,highlightlines={30},firstnumber=28,highlightcolor=yellow!40]{go}\n\tend = len(data) - 1\n\\end{minted}",
+		"Uses \\texttt{a`b} and \\emph{x\\_y}.",
+		"\\begin{itemize}\n\\tightlist\n\\item\n  first\n\\item\n  second\n\\end{itemize}",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestMDToTexNative(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"00_report.md":     "# Debug Report — example — 2026-01-02\n",
+		"90_conclusion.md": "\n## Root Cause\n\nOff by one.\n\n## Fix Applied\n\nClamp to `len(data)`.\n\n## Post-fix Verification\n\nTests pass.\n",
+		"frag_fix.md":      "# Fix Applied\n\n<template>\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tex, n, err := MDToTex(dir, "example", "2026-01-02", rendererNative)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("mdCount = %d, want 2 (frag_*.md skipped)", n)
+	}
+	for _, want := range []string{
+		`\texttt{example}`,
+		"\\begin{rootcausebox}\n\nOff by one.\n",
+		"\\begin{fixbox}\n\nClamp to \\texttt{len(data)}.\n",
+		`\subsection{Post-fix Verification}`,
+		`\end{document}`,
+	} {
+		if !strings.Contains(tex, want) {
+			t.Errorf("missing %q in:\n%s", want, tex)
+		}
+	}
+	if strings.Contains(tex, "<template>") {
+		t.Error("template fragment included in the report")
+	}
+	if _, _, err := MDToTex(dir, "", "", "markdown-it"); err == nil {
+		t.Error("unknown renderer accepted")
+	}
+}
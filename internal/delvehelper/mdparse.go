@@ -0,0 +1,326 @@
+// Markdown parsing for the native report renderers: just the subset the
+// report-* commands and templates produce (ATX headings, paragraphs, pipe
+// tables, fenced code with {key=value} attributes, lists, quotes, rules).
+package delvehelper
+
+import (
+	"regexp"
+	"strings"
+)
+
+type mdKind int
+
+const (
+	mdHeading mdKind = iota
+	mdParagraph
+	mdCode
+	mdTable
+	mdList
+	mdQuote
+	mdRule
+)
+
+// mdBlock is one block-level element. Text holds the inline Markdown of
+// headings and paragraphs and the raw content of code blocks.
+type mdBlock struct {
+	Kind    mdKind
+	Level   int         // heading level
+	ID      string      // heading identifier from a trailing {#id}
+	Text    string      // heading, paragraph, code or quote text
+	Lang    string      // code block language
+	Attrs   [][2]string // code block attributes in source order
+	Header  []string    // table header cells
+	Rows    [][]string  // table body cells
+	Items   []string    // list items
+	Ordered bool        // numbered list
+}
+
+var (
+	mdHeadingRE   = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdHeadingIDRE = regexp.MustCompile(`\s*\{#([A-Za-z][\w.:-]*)\}$`)
+	mdFenceRE     = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})\\s*(.*)$")
+	mdRuleRE      = regexp.MustCompile(`^ {0,3}([-*_])(\s*[-*_]){2,}\s*$`)
+	mdBulletRE    = regexp.MustCompile(`^ {0,3}[-*+]\s+(.*)$`)
+	mdNumberedRE  = regexp.MustCompile(`^ {0,3}\d+[.)]\s+(.*)$`)
+	mdSeparatorRE = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+)
+
+// parseMarkdown splits md into blocks.
+func parseMarkdown(md string) []mdBlock {
+	lines := strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n")
+	var blocks []mdBlock
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			i++
+		case mdFenceRE.MatchString(line):
+			b, next := parseFence(lines, i)
+			blocks = append(blocks, b)
+			i = next
+		case mdHeadingRE.MatchString(line):
+			m := mdHeadingRE.FindStringSubmatch(line)
+			b := mdBlock{Kind: mdHeading, Level: len(m[1]), Text: m[2]}
+			if id := mdHeadingIDRE.FindStringSubmatch(b.Text); id != nil {
+				b.Text, b.ID = strings.TrimSuffix(b.Text, id[0]), id[1]
+			}
+			blocks = append(blocks, b)
+			i++
+		case mdRuleRE.MatchString(line):
+			blocks = append(blocks, mdBlock{Kind: mdRule})
+			i++
+		case isTableLine(line) && i+1 < len(lines) && mdSeparatorRE.MatchString(lines[i+1]) && strings.Contains(lines[i+1], "-"):
+			b := mdBlock{Kind: mdTable, Header: splitTableRow(line)}
+			i += 2
+			for i < len(lines) && isTableLine(lines[i]) {
+				b.Rows = append(b.Rows, splitTableRow(lines[i]))
+				i++
+			}
+			blocks = append(blocks, b)
+		case mdBulletRE.MatchString(line) || mdNumberedRE.MatchString(line):
+			b := mdBlock{Kind: mdList, Ordered: !mdBulletRE.MatchString(line)}
+			re := mdBulletRE
+			if b.Ordered {
+				re = mdNumberedRE
+			}
+			for i < len(lines) {
+				if m := re.FindStringSubmatch(lines[i]); m != nil {
+					b.Items = append(b.Items, m[1])
+				} else if n := len(b.Items); n > 0 && strings.TrimSpace(lines[i]) != "" &&
+					(strings.HasPrefix(lines[i], "  ") || strings.HasPrefix(lines[i], "\t")) {
+					b.Items[n-1] += "\n" + strings.TrimSpace(lines[i])
+				} else {
+					break
+				}
+				i++
+			}
+			blocks = append(blocks, b)
+		case strings.HasPrefix(trimmed, ">"):
+			var q []string
+			for i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">") {
+				t := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+				q = append(q, strings.TrimPrefix(t, " "))
+				i++
+			}
+			blocks = append(blocks, mdBlock{Kind: mdQuote, Text: strings.Join(q, "\n")})
+		default:
+			var p []string
+			for i < len(lines) && strings.TrimSpace(lines[i]) != "" && !startsBlock(lines, i) {
+				p = append(p, lines[i])
+				i++
+			}
+			if len(p) == 0 { // a line startsBlock accepted but no case above took
+				p, i = append(p, line), i+1
+			}
+			blocks = append(blocks, mdBlock{Kind: mdParagraph, Text: strings.Join(p, "\n")})
+		}
+	}
+	return blocks
+}
+
+// startsBlock reports whether lines[i] interrupts a paragraph.
+func startsBlock(lines []string, i int) bool {
+	l := lines[i]
+	return mdFenceRE.MatchString(l) || mdHeadingRE.MatchString(l) || mdRuleRE.MatchString(l) ||
+		mdBulletRE.MatchString(l) || strings.HasPrefix(strings.TrimSpace(l), ">") ||
+		(isTableLine(l) && i+1 < len(lines) && mdSeparatorRE.MatchString(lines[i+1]))
+}
+
+// parseFence reads the fenced code block opening at lines[start] and returns
+// it with the index of the line after the closing fence. An unclosed fence
+// runs to the end of the input.
+func parseFence(lines []string, start int) (mdBlock, int) {
+	m := mdFenceRE.FindStringSubmatch(lines[start])
+	fence := m[1]
+	b := mdBlock{Kind: mdCode}
+	b.Lang, b.Attrs = parseFenceInfo(m[2])
+	var body []string
+	i := start + 1
+	for ; i < len(lines); i++ {
+		if closesFence(lines[i], fence) {
+			i++
+			break
+		}
+		body = append(body, lines[i])
+	}
+	b.Text = strings.Join(body, "\n")
+	return b, i
+}
+
+// parseFenceInfo reads "go {highlightlines=30 firstnumber=28}" or the pandoc
+// form "{.go highlightlines=30}".
+func parseFenceInfo(info string) (string, [][2]string) {
+	info = strings.TrimSpace(info)
+	lang, rest, _ := strings.Cut(info, "{")
+	lang = strings.TrimSpace(lang)
+	rest = strings.TrimSuffix(strings.TrimSpace(rest), "}")
+	var attrs [][2]string
+	for _, f := range strings.Fields(rest) {
+		switch {
+		case strings.HasPrefix(f, "."):
+			if lang == "" {
+				lang = f[1:]
+			}
+		case strings.Contains(f, "="):
+			k, v, _ := strings.Cut(f, "=")
+			attrs = append(attrs, [2]string{k, strings.Trim(v, `"`)})
+		}
+	}
+	if lang == "" {
+		lang = "text"
+	}
+	return lang, attrs
+}
+
+func isTableLine(line string) bool {
+	t := strings.TrimSpace(line)
+	return strings.HasPrefix(t, "|") && len(t) > 1 && strings.Contains(t[1:], "|")
+}
+
+// splitTableRow splits a pipe table row into cells on unescaped pipes.
+// As in GFM, "\|" is a literal pipe even inside a code span.
+func splitTableRow(line string) []string {
+	t := strings.TrimSpace(line)
+	t = strings.TrimPrefix(t, "|")
+	if strings.HasSuffix(t, "|") && !strings.HasSuffix(t, `\|`) {
+		t = t[:len(t)-1]
+	}
+	var cells []string
+	var cur strings.Builder
+	for i := 0; i < len(t); i++ {
+		switch {
+		case t[i] == '\\' && i+1 < len(t) && t[i+1] == '|':
+			cur.WriteByte('|')
+			i++
+		case t[i] == '|':
+			cells = append(cells, strings.TrimSpace(cur.String()))
+			cur.Reset()
+		default:
+			cur.WriteByte(t[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cur.String()))
+}
+
+// mdInline is one inline element of heading, paragraph or cell text.
+type mdInline struct {
+	Kind  string // text | code | strong | emph | link | span | break
+	Text  string // literal text (text, code) or inner Markdown (strong, emph, link, span)
+	URL   string
+	Class string // span classes, space separated
+}
+
+// parseInline splits inline Markdown into text, code spans (any backtick
+// run length), **strong**, *emph*, [links](url), pandoc [spans]{.class}
+// and hard line breaks.
+// Backslash escapes of punctuation become literal text.
+func parseInline(s string) []mdInline {
+	var out []mdInline
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			out = append(out, mdInline{Kind: "text", Text: text.String()})
+			text.Reset()
+		}
+	}
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && s[i+1] == '\n':
+			flush()
+			out = append(out, mdInline{Kind: "break"})
+			i += 2
+			continue
+		case c == '\\' && i+1 < len(s) && strings.IndexByte("\\`*_{}[]()#+-.!|<>~\"'$&%^", s[i+1]) >= 0:
+			text.WriteByte(s[i+1])
+			i += 2
+			continue
+		case c == ' ' && strings.HasPrefix(s[i:], "  \n"):
+			flush()
+			out = append(out, mdInline{Kind: "break"})
+			i += 3
+			for i < len(s) && s[i] == ' ' {
+				i++
+			}
+			continue
+		case c == '`':
+			n := 0
+			for i+n < len(s) && s[i+n] == '`' {
+				n++
+			}
+			if end := findBacktickRun(s[i+n:], n); end >= 0 {
+				flush()
+				code := s[i+n : i+n+end]
+				if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+					code = code[1 : len(code)-1]
+				}
+				out = append(out, mdInline{Kind: "code", Text: strings.ReplaceAll(code, "\n", " ")})
+				i += n + end + n
+				continue
+			}
+			text.WriteString(s[i : i+n])
+			i += n
+			continue
+		case strings.HasPrefix(s[i:], "**"):
+			if end := strings.Index(s[i+2:], "**"); end > 0 {
+				flush()
+				out = append(out, mdInline{Kind: "strong", Text: s[i+2 : i+2+end]})
+				i += end + 4
+				continue
+			}
+		case c == '*' && i+1 < len(s) && s[i+1] != ' ':
+			if end := strings.IndexByte(s[i+1:], '*'); end > 0 && s[i+end] != ' ' {
+				flush()
+				out = append(out, mdInline{Kind: "emph", Text: s[i+1 : i+1+end]})
+				i += end + 2
+				continue
+			}
+		case c == '[':
+			if close := strings.IndexByte(s[i:], ']'); close > 1 && strings.HasPrefix(s[i+close:], "]{.") {
+				if end := strings.IndexByte(s[i+close:], '}'); end > 0 {
+					flush()
+					attrs := strings.Fields(s[i+close+2 : i+close+end])
+					for k := range attrs {
+						attrs[k] = strings.TrimPrefix(attrs[k], ".")
+					}
+					out = append(out, mdInline{Kind: "span", Text: s[i+1 : i+close], Class: strings.Join(attrs, " ")})
+					i += close + end + 1
+					continue
+				}
+			}
+			if close := strings.Index(s[i:], "]("); close > 0 {
+				if end := strings.IndexByte(s[i+close:], ')'); end > 0 {
+					flush()
+					out = append(out, mdInline{Kind: "link", Text: s[i+1 : i+close], URL: s[i+close+2 : i+close+end]})
+					i += close + end + 1
+					continue
+				}
+			}
+		}
+		text.WriteByte(c)
+		i++
+	}
+	flush()
+	return out
+}
+
+// findBacktickRun returns the index in s of the next run of exactly n
+// backticks, or -1.
+func findBacktickRun(s string, n int) int {
+	for i := 0; i < len(s); {
+		if s[i] != '`' {
+			i++
+			continue
+		}
+		j := i
+		for j < len(s) && s[j] == '`' {
+			j++
+		}
+		if j-i == n {
+			return i
+		}
+		i = j
+	}
+	return -1
+}
@@ -0,0 +1,186 @@
+// Target program I/O: redirect files under .dlv/ and the output command.
+package delvehelper
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	targetInFile  = "target.in"
+	targetOutFile = "target.out"
+	targetErrFile = "target.err"
+	outputPosFile = "output.pos" // read offsets for output -since-last
+)
+
+// prepareRedirects creates the target I/O files in dlvDir and returns the
+// matching dlv --redirect arguments. Paths are absolute because dlv may run
+// the target from a different working directory.
+func prepareRedirects(dlvDir, stdinFile string) ([]string, error) {
+	abs, err := filepath.Abs(dlvDir)
+	if err != nil {
+		return nil, err
+	}
+	var args []string
+	if stdinFile != "" {
+		data, err := os.ReadFile(stdinFile)
+		if err != nil {
+			return nil, fmt.Errorf("read stdin file: %w", err)
+		}
+		in := filepath.Join(abs, targetInFile)
+		if err := os.WriteFile(in, data, 0644); err != nil {
+			return nil, err
+		}
+		args = append(args, "--redirect", "stdin:"+in)
+	}
+	for _, r := range []struct{ stream, name string }{
+		{"stdout", targetOutFile},
+		{"stderr", targetErrFile},
+	} {
+		path := filepath.Join(abs, r.name)
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			return nil, err
+		}
+		args = append(args, "--redirect", r.stream+":"+path)
+	}
+	// New session, new files: forget where the previous output -since-last stopped.
+	_ = os.Remove(filepath.Join(abs, outputPosFile))
+	return args, nil
+}
+
+func readOutputPos(dlvDir string) map[string]int64 {
+	pos := map[string]int64{}
+	if b, err := os.ReadFile(filepath.Join(dlvDir, outputPosFile)); err == nil {
+		_ = json.Unmarshal(b, &pos)
+	}
+	return pos
+}
+
+func writeOutputPos(dlvDir string, pos map[string]int64) error {
+	b, err := json.Marshal(pos)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dlvDir, outputPosFile), b, 0644)
+}
+
+// readFrom returns the contents of path starting at offset and the new end offset.
+// A file that shrank (re-created by a new session) is read from the start.
+func readFrom(path string, offset int64) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", offset, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return "", offset, err
+	}
+	if fi.Size() < offset {
+		offset = 0
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return "", offset, err
+	}
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return "", offset, err
+	}
+	return string(b), offset + int64(len(b)), nil
+}
+
+// cmdOutput prints the redirected target stdout/stderr.
+func cmdOutput(args []string) error {
+	fs := flag.NewFlagSet("output", flag.ContinueOnError)
+	follow := fs.Bool("follow", false, "keep printing new output until the session ends (Ctrl-C to stop)")
+	sinceLast := fs.Bool("since-last", false, "print only output written since the previous output call")
+	stream := fs.String("stream", "both", "which stream to print: out | err | both")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var names []string
+	switch *stream {
+	case "out":
+		names = []string{targetOutFile}
+	case "err":
+		names = []string{targetErrFile}
+	case "both":
+		names = []string{targetOutFile, targetErrFile}
+	default:
+		return fmt.Errorf("invalid -stream %q (want out, err or both)", *stream)
+	}
+
+	dlvDir := getDlvDir()
+	if _, err := os.Stat(filepath.Join(dlvDir, targetOutFile)); err != nil {
+		return fmt.Errorf("no target output in %s (start the session with 'delve-helper start -redirect')", dlvDir)
+	}
+	pos := map[string]int64{}
+	if *sinceLast {
+		pos = readOutputPos(dlvDir)
+	}
+	printNew := func(label bool) error {
+		for _, name := range names {
+			text, end, err := readFrom(filepath.Join(dlvDir, name), pos[name])
+			if err != nil {
+				return err
+			}
+			pos[name] = end
+			if text == "" {
+				continue
+			}
+			if label && len(names) > 1 {
+				fmt.Printf("--- %s ---\n", name)
+			}
+			fmt.Print(text)
+			if !strings.HasSuffix(text, "\n") && !*follow {
+				fmt.Println()
+			}
+		}
+		return writeOutputPos(dlvDir, pos)
+	}
+	if err := printNew(!*follow); err != nil {
+		return err
+	}
+	for *follow {
+		// The session is over once stop has removed .dlv/addr.
+		if _, err := os.Stat(getAddrFilePath()); err != nil {
+			return nil
+		}
+		time.Sleep(200 * time.Millisecond)
+		if err := printNew(false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tailProgramOutput returns the last n lines of the redirected target output,
+// stdout first, for attaching to evidence blocks. It is empty when the
+// session was not started with -redirect.
+func tailProgramOutput(n int) string {
+	var sb strings.Builder
+	for _, name := range []string{targetOutFile, targetErrFile} {
+		b, err := os.ReadFile(filepath.Join(getDlvDir(), name))
+		if err != nil || len(b) == 0 {
+			continue
+		}
+		lines := strings.Split(strings.TrimRight(string(b), "\n"), "\n")
+		if len(lines) > n {
+			lines = lines[len(lines)-n:]
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		if name == targetErrFile {
+			sb.WriteString("[stderr]\n")
+		}
+		sb.WriteString(strings.Join(lines, "\n"))
+	}
+	return sb.String()
+}
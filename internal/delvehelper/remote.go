@@ -0,0 +1,135 @@
+// Remote Delve servers: connect records a session for a server that
+// delve-helper did not spawn, and path substitution rules map source paths
+// from the build machine (e.g. a container's /app) to the local checkout.
+package delvehelper
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const substitutePathFile = "substitute-path.json"
+
+// substituteFlag collects repeated -substitute-path from=to options. from is
+// the directory as recorded in the binary, to the same directory locally.
+type substituteFlag [][2]string
+
+func (f *substituteFlag) String() string {
+	var parts []string
+	for _, r := range *f {
+		parts = append(parts, r[0]+"="+r[1])
+	}
+	return strings.Join(parts, ",")
+}
+
+func (f *substituteFlag) Set(v string) error {
+	from, to, ok := strings.Cut(v, "=")
+	if !ok || from == "" || to == "" {
+		return fmt.Errorf("invalid rule %q (want from=to, e.g. /app=$PWD)", v)
+	}
+	if abs, err := filepath.Abs(to); err == nil {
+		to = abs
+	}
+	*f = append(*f, [2]string{from, to})
+	return nil
+}
+
+// loadSubstituteRules returns the rules saved for the current session, or nil.
+func loadSubstituteRules() [][2]string {
+	b, err := os.ReadFile(filepath.Join(getDlvDir(), substitutePathFile))
+	if err != nil {
+		return nil
+	}
+	var rules [][2]string
+	if err := json.Unmarshal(b, &rules); err != nil {
+		return nil
+	}
+	return rules
+}
+
+func saveSubstituteRules(dlvDir string, rules [][2]string) error {
+	path := filepath.Join(dlvDir, substitutePathFile)
+	if len(rules) == 0 {
+		os.Remove(path)
+		return nil
+	}
+	b, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0644)
+}
+
+// localPath maps a source path reported by Delve to the local checkout using
+// the session's substitution rules; paths no rule matches are returned as is.
+func localPath(file string) string {
+	for _, r := range loadSubstituteRules() {
+		from := strings.TrimSuffix(r[0], "/")
+		if file == from || strings.HasPrefix(file, from+"/") {
+			return r[1] + strings.TrimPrefix(file, from)
+		}
+	}
+	return file
+}
+
+// cmdConnect validates a headless Delve server at host:port and records it as
+// the current session so every other command talks to it.
+func cmdConnect(args []string) error {
+	fs := flag.NewFlagSet("connect", flag.ContinueOnError)
+	var rules substituteFlag
+	fs.Var(&rules, "substitute-path", "map a build-time source dir to a local dir: from=to (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: connect [-substitute-path from=to]... <host:port>")
+	}
+	addr := fs.Arg(0)
+
+	dlvDir := getDlvDir()
+	if _, err := os.Stat(filepath.Join(dlvDir, "addr")); err == nil && sessionProblem(dlvDir) == "" {
+		if _, hasPid := readSessionPid(dlvDir); hasPid {
+			return fmt.Errorf("a local session is already recorded in %s; run 'delve-helper stop' first or pass -session NAME", dlvDir)
+		}
+	}
+
+	client, err := newClientAt(addr)
+	if err != nil {
+		return err
+	}
+	defer client.Disconnect(false)
+	state, err := client.GetStateNonBlocking()
+	if err != nil && !isExitError(err) {
+		return fmt.Errorf("%s does not look like a Delve API v2 server: %w", addr, err)
+	}
+	if !client.IsMulticlient() {
+		fmt.Println("warning: server was not started with --accept-multiclient; it may exit when this connection closes")
+	}
+
+	if err := os.MkdirAll(dlvDir, 0755); err != nil {
+		return err
+	}
+	for _, name := range []string{"pid", "daemon.sock", "daemon.pid"} {
+		os.Remove(filepath.Join(dlvDir, name))
+	}
+	addrFile := filepath.Join(dlvDir, "addr")
+	if err := os.WriteFile(addrFile, []byte(addr+"\n"), 0644); err != nil {
+		return err
+	}
+	_ = os.WriteFile(filepath.Join(dlvDir, "target"), []byte("remote "+addr+"\n"), 0644)
+	if err := saveSubstituteRules(dlvDir, rules); err != nil {
+		return err
+	}
+	fmt.Println("connected to", addr, "- address written to", addrFile)
+	for _, r := range rules {
+		fmt.Printf("substitute-path %s -> %s\n", r[0], r[1])
+	}
+	if state != nil {
+		return printState(state)
+	}
+	return nil
+}
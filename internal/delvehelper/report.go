@@ -2,6 +2,7 @@
 package delvehelper
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
@@ -24,12 +25,16 @@ func cmdReportBuild(args []string) error {
 	verbose := fs.Bool("v", false, "write generated LaTeX to stderr for debugging")
 	doPDF := fs.Bool("pdf", false, "compile to PDF with pdflatex after generating .tex")
 	outPath := fs.String("out", "", "copy PDF to this path (requires -pdf)")
+	doHTML := fs.Bool("html", false, "also write a self-contained debug_report.html")
+	force := fs.Bool("force", false, "build even when report-check finds problems")
+	doJSON := fs.Bool("json", false, "also export the report model as debug_report.json")
+	renderer := fs.String("renderer", rendererAuto, "Markdown to LaTeX converter: auto (pandoc if installed, else native), native or pandoc")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
 	rest := fs.Args()
 	if len(rest) != 1 {
-		return fmt.Errorf("usage: report-build [-pkg pkg] [-date date] [-pdf] [-out path] <dbgdir>")
+		return fmt.Errorf("usage: report-build [-pkg pkg] [-date date] [-renderer auto|native|pandoc] [-html] [-json] [-pdf] [-out path] [-force] <dbgdir>")
 	}
 	dbgDir := rest[0]
 
@@ -42,7 +47,20 @@ func cmdReportBuild(args []string) error {
 		}
 	}
 
-	tex, mdCount, err := MDToTex(dbgDir, *pkg, *date)
+	if !*force {
+		problems, err := runReportCheck(dbgDir)
+		if err != nil {
+			return err
+		}
+		if len(problems) > 0 {
+			for _, p := range problems {
+				fmt.Fprintln(os.Stderr, "  - "+p)
+			}
+			return fmt.Errorf("report-check found %d problem(s) in %s; complete the report or pass -force", len(problems), dbgDir)
+		}
+	}
+
+	tex, mdCount, err := MDToTex(dbgDir, *pkg, *date, *renderer)
 	if err != nil {
 		return err
 	}
@@ -62,6 +80,23 @@ func cmdReportBuild(args []string) error {
 	}
 	fmt.Printf("wrote %s from %d markdown fragments\n", reportPath, mdCount)
 
+	if *doHTML {
+		page, _, err := MDToHTML(dbgDir, *pkg, *date)
+		if err != nil {
+			return err
+		}
+		htmlPath := filepath.Join(dbgDir, "debug_report.html")
+		if err := os.WriteFile(htmlPath, []byte(page), 0644); err != nil {
+			return fmt.Errorf("write %s: %w", htmlPath, err)
+		}
+		fmt.Printf("wrote %s\n", htmlPath)
+	}
+	if *doJSON {
+		if err := exportReportJSON(dbgDir); err != nil {
+			return err
+		}
+	}
+
 	if *doPDF {
 		if err := TexToPDF(dbgDir); err != nil {
 			return err
@@ -74,3 +109,24 @@ func cmdReportBuild(args []string) error {
 	}
 	return nil
 }
+
+// exportReportJSON writes the report model of dbgDir to debug_report.json.
+func exportReportJSON(dbgDir string) error {
+	m, ok, err := loadReport(dbgDir)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%s has no %s (the report was started before report.json existed); re-run report-init on a new dir", dbgDir, reportModelFile)
+	}
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	jsonPath := filepath.Join(dbgDir, "debug_report.json")
+	if err := os.WriteFile(jsonPath, append(b, '\n'), 0644); err != nil {
+		return fmt.Errorf("write %s: %w", jsonPath, err)
+	}
+	fmt.Printf("wrote %s\n", jsonPath)
+	return nil
+}
@@ -0,0 +1,477 @@
+// Report model: report.json holds the typed report (hypotheses, trace rows,
+// evidence, root cause, fixes, verification) that the report-* commands
+// update. The Markdown fragments are rendered from it after every update,
+// and report-build renders LaTeX, HTML and JSON from the same model.
+//
+// Artifact dirs written before report.json existed have no model; the
+// report-* commands keep appending to their fragments as before.
+package delvehelper
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	reportModelFile    = "report.json"
+	reportModelVersion = 1
+)
+
+// reportModel is the content of report.json. Seq is the last sequence number
+// handed out; every item records the Seq it was added at, so the order of
+// events across sections (e.g. a fix before any evidence) is known.
+type reportModel struct {
+	Version       int                `json:"version"`
+	Package       string             `json:"package"`
+	Date          string             `json:"date"`
+	Seq           int                `json:"seq"`
+	Hypotheses    []reportHypothesis `json:"hypotheses"`
+	Trace         []reportTraceRow   `json:"trace"`
+	Evidence      []reportEvidence   `json:"evidence"`
+	RootCauses    []reportText       `json:"root_causes"`
+	Fixes         []reportFix        `json:"fixes"`
+	Verifications []reportText       `json:"verifications"`
+	Iterations    []reportIteration  `json:"iterations,omitempty"`
+}
+
+// reportHypothesis is one hypothesis. Evidence lists the evidence blocks
+// (1-based, as in E1) that confirmed or refuted it.
+type reportHypothesis struct {
+	Seq      int    `json:"seq"`
+	ID       string `json:"id,omitempty"`
+	Loc      string `json:"loc"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+	Status   string `json:"status,omitempty"`
+	Evidence []int  `json:"evidence,omitempty"`
+}
+
+// Hypothesis statuses; an empty Status is open.
+const (
+	hypOpen      = "open"
+	hypConfirmed = "confirmed"
+	hypRefuted   = "refuted"
+)
+
+type reportTraceRow struct {
+	Seq    int    `json:"seq"`
+	N      int    `json:"n"`
+	Action string `json:"action"`
+	Loc    string `json:"loc"`
+	Reason string `json:"reason"`
+}
+
+// reportEvidence is one evidence block. Source lines and program output are
+// captured when the block is written, since both change after the fix.
+type reportEvidence struct {
+	Seq      int             `json:"seq"`
+	Loc      string          `json:"loc"`
+	Source   *reportSource   `json:"source,omitempty"`
+	Args     string          `json:"args,omitempty"`
+	Locals   string          `json:"locals,omitempty"`
+	Stack    string          `json:"stack,omitempty"`
+	Prints   []evidencePrint `json:"prints,omitempty"`
+	PrintVal string          `json:"print,omitempty"`
+	Output   string          `json:"output,omitempty"`
+	Obs      string          `json:"observation,omitempty"`
+}
+
+type reportSource struct {
+	File      string   `json:"file"`
+	First     int      `json:"first_line"`
+	Highlight int      `json:"highlight_line"`
+	Lines     []string `json:"lines"`
+}
+
+type reportText struct {
+	Seq  int    `json:"seq"`
+	Text string `json:"text"`
+}
+
+// reportFix is a fix attempt; a rejected one records why it did not work.
+type reportFix struct {
+	Seq      int    `json:"seq"`
+	Text     string `json:"text"`
+	Diff     string `json:"diff,omitempty"`
+	Rejected bool   `json:"rejected,omitempty"`
+	Why      string `json:"why,omitempty"`
+}
+
+// reportIteration starts iteration N of the fix loop (Steps 3 → 4 → 5).
+// Trace rows, evidence and fixes added after it, up to the next one, belong
+// to it.
+type reportIteration struct {
+	Seq   int    `json:"seq"`
+	N     int    `json:"n"`
+	Label string `json:"label,omitempty"`
+}
+
+// next returns the sequence number for a new item.
+func (m *reportModel) next() int {
+	m.Seq++
+	return m.Seq
+}
+
+// loadReport reads dir/report.json; ok is false when the dir has no model.
+func loadReport(dir string) (*reportModel, bool, error) {
+	b, err := os.ReadFile(rfile(dir, reportModelFile))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var m reportModel
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, false, fmt.Errorf("parse %s: %w", reportModelFile, err)
+	}
+	for i := range m.Hypotheses {
+		if m.Hypotheses[i].ID == "" {
+			m.Hypotheses[i].ID = fmt.Sprintf("H%d", i+1)
+		}
+	}
+	return &m, true, nil
+}
+
+// iteration returns the iteration an item added at seq belongs to, or nil
+// before the first report-iteration-start.
+func (m *reportModel) iteration(seq int) *reportIteration {
+	var it *reportIteration
+	for i := range m.Iterations {
+		if m.Iterations[i].Seq < seq {
+			it = &m.Iterations[i]
+		}
+	}
+	return it
+}
+
+// hypothesis returns the hypothesis with the given id (any case), or nil.
+func (m *reportModel) hypothesis(id string) *reportHypothesis {
+	for i := range m.Hypotheses {
+		if id != "" && strings.EqualFold(m.Hypotheses[i].ID, id) {
+			return &m.Hypotheses[i]
+		}
+	}
+	return nil
+}
+
+// nextHypothesisID returns the first unused id of the form H<n>.
+func (m *reportModel) nextHypothesisID() string {
+	for n := len(m.Hypotheses) + 1; ; n++ {
+		if id := fmt.Sprintf("H%d", n); m.hypothesis(id) == nil {
+			return id
+		}
+	}
+}
+
+// evidenceRef resolves an evidence reference to a block number: "E2" or
+// "2", or a location label, which names its most recent block.
+func (m *reportModel) evidenceRef(ref string) (int, error) {
+	if n, err := strconv.Atoi(strings.TrimPrefix(strings.TrimPrefix(ref, "E"), "e")); err == nil {
+		if n < 1 || n > len(m.Evidence) {
+			return 0, fmt.Errorf("no evidence block E%d (the report has %d)", n, len(m.Evidence))
+		}
+		return n, nil
+	}
+	for i := len(m.Evidence) - 1; i >= 0; i-- {
+		if loc := m.Evidence[i].Loc; loc == ref || strings.HasSuffix(loc, "/"+ref) {
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("no evidence block for %q: use E<n> or the location of a block", ref)
+}
+
+// saveReport writes the model and re-renders the fragments it owns.
+func saveReport(dir string, m *reportModel) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(rfile(dir, reportModelFile), append(b, '\n'), 0644); err != nil {
+		return fmt.Errorf("write %s: %w", reportModelFile, err)
+	}
+	for name, content := range m.fragments() {
+		path := rfile(dir, name)
+		if content == "" {
+			os.Remove(path)
+			continue
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return fmt.Errorf("write %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// updateReport applies update to the model in dir and saves it. Without a
+// model it appends legacy to legacyFile instead.
+func updateReport(dir string, update func(m *reportModel), legacyFile, legacy string) error {
+	m, ok, err := loadReport(dir)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return appendToFile(rfile(dir, legacyFile), legacy)
+	}
+	update(m)
+	return saveReport(dir, m)
+}
+
+// hasLegacyFragments reports whether dir already has report content that
+// was written without a model.
+func hasLegacyFragments(dir string) bool {
+	for _, name := range []string{reportTraceFile, reportEvidFile, reportConcFile} {
+		if _, err := os.Stat(rfile(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// fragments renders the model as the Markdown files it owns.
+func (m *reportModel) fragments() map[string]string {
+	var main, trace, evid, conc strings.Builder
+	fmt.Fprintf(&main, "# Debug Report — %s — %s\n\n", m.Package, m.Date)
+	for i, h := range m.Hypotheses {
+		if i == 0 {
+			main.WriteString("\n" + hypothesesHeaderMD)
+		}
+		main.WriteString(h.row())
+	}
+	// Trace rows and evidence are grouped under a heading per iteration.
+	var cur *reportIteration
+	for i, r := range m.Trace {
+		it := m.iteration(r.Seq)
+		if i == 0 {
+			trace.WriteString("## Debugging Trace\n")
+		}
+		if i == 0 || it != cur {
+			if it != nil {
+				trace.WriteString("\n### " + it.title() + "\n")
+			}
+			trace.WriteString("\n" + traceTableMD)
+			cur = it
+		}
+		trace.WriteString(r.markdown())
+	}
+	cur = nil
+	for i, e := range m.Evidence {
+		it := m.iteration(e.Seq)
+		if i == 0 {
+			evid.WriteString(evidenceHeaderMD)
+		}
+		if it != nil && it != cur {
+			evid.WriteString("\n### " + it.title() + "\n")
+		}
+		cur = it
+		evid.WriteString(e.markdown(i + 1))
+	}
+	if len(m.Iterations) > 0 {
+		conc.WriteString(m.iterationsMD())
+	}
+	for _, s := range m.conclusion() {
+		conc.WriteString(s.md)
+	}
+	return map[string]string{
+		reportMainFile:  main.String(),
+		reportTraceFile: trace.String(),
+		reportEvidFile:  evid.String(),
+		reportConcFile:  conc.String(),
+	}
+}
+
+// reportSection is one rendered conclusion section and when it was added.
+type reportSection struct {
+	seq int
+	md  string
+}
+
+// conclusion returns root causes, fixes and verifications as Markdown in
+// the order they were recorded.
+func (m *reportModel) conclusion() []reportSection {
+	var out []reportSection
+	for _, r := range m.RootCauses {
+		out = append(out, reportSection{r.Seq, rootCauseMD(r.Text)})
+	}
+	for _, f := range m.Fixes {
+		title := ""
+		if it := m.iteration(f.Seq); it != nil {
+			title = it.title()
+		}
+		out = append(out, reportSection{f.Seq, f.markdown(title)})
+	}
+	for _, v := range m.Verifications {
+		out = append(out, reportSection{v.Seq, verificationMD(v.Text)})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].seq < out[j].seq })
+	return out
+}
+
+const (
+	hypothesesHeaderMD = "## Hypotheses\n\n| ID | Suspected location | Expected | Actual | Status |\n| -- | ------------------ | -------- | ------ | ------ |\n"
+	traceTableMD       = "| # | Action | Location | Reasoning |\n| - | ------ | -------- | --------- |\n"
+	traceHeaderMD      = "## Debugging Trace\n\n" + traceTableMD
+	iterationsHeaderMD = "## Iterations\n\n| # | Label | Trace rows | Evidence | Outcome |\n| - | ----- | ---------- | -------- | ------- |\n"
+	evidenceHeaderMD   = "## Breakpoints & Evidence\n"
+)
+
+// markdown renders the hypothesis as a section of its own, as written to
+// artifact dirs without a model.
+func (h reportHypothesis) markdown() string {
+	title := "Hypothesis"
+	if h.ID != "" {
+		title += " " + mdLine(h.ID)
+	}
+	return fmt.Sprintf("## %s\n\nSuspected location: %s\n\nExpected: %s\n\nActual: %s\n",
+		title, mdCodeSpan(h.Loc), mdLine(h.Expected), mdLine(h.Actual))
+}
+
+// row renders the hypothesis as a row of the Hypotheses table: its status
+// badge links to the evidence behind it.
+func (h reportHypothesis) row() string {
+	status := h.Status
+	if status == "" {
+		status = hypOpen
+	}
+	cell := fmt.Sprintf("[%s]{.badge .%s}", status, status)
+	for i, n := range h.Evidence {
+		sep := " "
+		if i > 0 {
+			sep = ", "
+		}
+		cell += fmt.Sprintf("%s[E%d](#%s)", sep, n, evidenceAnchor(n))
+	}
+	return fmt.Sprintf("| %s | %s | %s | %s | %s |\n", mdCell(h.ID), mdCell(mdCodeSpan(h.Loc)),
+		mdCell(h.Expected), mdCell(h.Actual), cell)
+}
+
+// evidenceAnchor is the heading identifier of evidence block n.
+func evidenceAnchor(n int) string { return fmt.Sprintf("evidence-%d", n) }
+
+func (r reportTraceRow) markdown() string {
+	return fmt.Sprintf("| %d | %s | %s | %s |\n", r.N, mdCell(r.Action), mdCell(mdCodeSpan(r.Loc)), mdCell(r.Reason))
+}
+
+// markdown renders evidence block n (E1, E2, …), or an unnumbered block
+// for artifact dirs without a model when n is 0.
+func (e reportEvidence) markdown(n int) string {
+	var sb strings.Builder
+	switch {
+	case n > 0 && e.Loc == "":
+		sb.WriteString(fmt.Sprintf("\n### E%d {#%s}\n\n", n, evidenceAnchor(n)))
+	case n > 0:
+		sb.WriteString(fmt.Sprintf("\n### E%d — %s {#%s}\n\n", n, mdLine(e.Loc), evidenceAnchor(n)))
+	default:
+		sb.WriteString(fmt.Sprintf("\n### %s\n\n", mdLine(e.Loc)))
+	}
+	if e.Source != nil {
+		sb.WriteString("**Source context:**\n\n")
+		sb.WriteString(fmtSourceBlock(e.Source.Lines, e.Source.First, e.Source.Highlight))
+		sb.WriteString("\n")
+	}
+	fmtBlock := func(label, text string) {
+		if text == "" {
+			return
+		}
+		sb.WriteString(fmt.Sprintf("**%s:**\n\n%s\n", label, mdCodeBlock("text", strings.TrimRight(text, "\n"))))
+	}
+	fmtBlock("Args", e.Args)
+	fmtBlock("Locals", e.Locals)
+	fmtBlock("Stack", e.Stack)
+	for _, p := range e.Prints {
+		sb.WriteString(fmt.Sprintf("**Print %s:**\n\n%s\n",
+			mdCodeSpan(p.Expr), mdCodeBlock("text", strings.TrimRight(p.Val, "\n"))))
+	}
+	fmtBlock("Print", e.PrintVal)
+	fmtBlock("Program output", e.Output)
+	if e.Obs != "" {
+		sb.WriteString(fmt.Sprintf("**Observation:** %s\n", mdLine(e.Obs)))
+	}
+	return sb.String()
+}
+
+func rootCauseMD(text string) string {
+	return fmt.Sprintf("\n## Root Cause\n\n%s\n", mdText(text))
+}
+
+// markdown renders the fix; iteration names the iteration it was tried in,
+// if any, for a rejected fix.
+func (f reportFix) markdown(iteration string) string {
+	var sb strings.Builder
+	switch {
+	case f.Rejected && iteration != "":
+		sb.WriteString("\n## Rejected Fix (" + iteration + ")\n\n")
+	case f.Rejected:
+		sb.WriteString("\n## Rejected Fix\n\n")
+	default:
+		sb.WriteString("\n## Fix Applied\n\n")
+	}
+	sb.WriteString(mdText(f.Text))
+	sb.WriteString("\n")
+	if f.Why != "" {
+		sb.WriteString("\n**Why it failed:** " + mdLine(f.Why) + "\n")
+	}
+	if f.Diff != "" {
+		sb.WriteString("\n" + mdCodeBlock("diff", strings.TrimRight(f.Diff, "\n")))
+	}
+	return sb.String()
+}
+
+func verificationMD(text string) string {
+	return fmt.Sprintf("\n## Post-fix Verification\n\n%s\n", mdText(text))
+}
+
+func (it reportIteration) title() string {
+	if it.Label == "" {
+		return fmt.Sprintf("Iteration %d", it.N)
+	}
+	return fmt.Sprintf("Iteration %d: %s", it.N, mdLine(it.Label))
+}
+
+// iterationsMD renders the summary table of the fix loop: what each
+// iteration traced, the evidence it collected and how its fix fared.
+func (m *reportModel) iterationsMD() string {
+	var sb strings.Builder
+	sb.WriteString("\n" + iterationsHeaderMD)
+	for i := range m.Iterations {
+		it := &m.Iterations[i]
+		var rows []int
+		for _, r := range m.Trace {
+			if m.iteration(r.Seq) == it {
+				rows = append(rows, r.N)
+			}
+		}
+		trace := "—"
+		if len(rows) > 0 {
+			trace = fmt.Sprintf("%d–%d", rows[0], rows[len(rows)-1])
+			if len(rows) == 1 {
+				trace = strconv.Itoa(rows[0])
+			}
+		}
+		var evid []string
+		for n, e := range m.Evidence {
+			if m.iteration(e.Seq) == it {
+				evid = append(evid, fmt.Sprintf("[E%d](#%s)", n+1, evidenceAnchor(n+1)))
+			}
+		}
+		if len(evid) == 0 {
+			evid = []string{"—"}
+		}
+		outcome := "[no fix]{.badge .open}"
+		for _, f := range m.Fixes {
+			switch {
+			case m.iteration(f.Seq) != it:
+			case f.Rejected:
+				outcome = "[rejected]{.badge .refuted} " + mdCell(f.Why)
+			default:
+				outcome = "[applied]{.badge .confirmed}"
+			}
+		}
+		fmt.Fprintf(&sb, "| %d | %s | %s | %s | %s |\n", it.N, mdCell(it.Label), trace, strings.Join(evid, ", "), outcome)
+	}
+	return sb.String()
+}
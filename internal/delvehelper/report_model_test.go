@@ -0,0 +1,200 @@
+package delvehelper
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runCommands dispatches each command line in order, skipping nil ones, and
+// stops the test at the first that fails.
+func runCommands(t *testing.T, steps [][]string) {
+	t.Helper()
+	for _, args := range steps {
+		if args == nil {
+			continue
+		}
+		if err := dispatch(args[0], args[1:]); err != nil {
+			t.Fatalf("%s: %v", args[0], err)
+		}
+	}
+}
+
+func TestReportModelCommands(t *testing.T) {
+	dir := t.TempDir()
+	runCommands(t, [][]string{
+		{"report-init", "-pkg", "example", "-date", "2026-01-02", dir},
+		{"report-trace-row", "-action", "set", "-loc", "pipeline.go:30", "-reason", "", dir},
+		{"report-fix", "-text", "Clamp.", "-diff", "-a\n+b", dir},
+		{"report-root-cause", "-text", "Off by one.", dir},
+		{"report-annotate", "1", "-reason", "entry of Window", dir},
+	})
+	m, ok, err := loadReport(dir)
+	if err != nil || !ok {
+		t.Fatalf("loadReport: ok=%v err=%v", ok, err)
+	}
+	if m.Seq != 3 || len(m.Trace) != 1 || m.Trace[0].Reason != "entry of Window" {
+		t.Errorf("model = %+v", m)
+	}
+	if m.Fixes[0].Seq >= m.RootCauses[0].Seq {
+		t.Errorf("fix seq %d not before root cause seq %d", m.Fixes[0].Seq, m.RootCauses[0].Seq)
+	}
+	for name, want := range m.fragments() {
+		got, _ := os.ReadFile(filepath.Join(dir, name))
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	conc, _ := os.ReadFile(filepath.Join(dir, reportConcFile))
+	if i, j := strings.Index(string(conc), "## Fix Applied"), strings.Index(string(conc), "## Root Cause"); i < 0 || j < i {
+		t.Errorf("conclusion not in recorded order:\n%s", conc)
+	}
+}
+
+func TestReportLegacyDirAppends(t *testing.T) {
+	dir := t.TempDir()
+	trace := filepath.Join(dir, reportTraceFile)
+	if err := os.WriteFile(trace, []byte(traceHeaderMD+"| 1 | set | `a.go:1` | old |\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := dispatch("report-trace-row", []string{"-action", "hit", "-loc", "a.go:1", "-reason", "new", dir}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, reportModelFile)); !os.IsNotExist(err) {
+		t.Errorf("report.json created in a legacy dir")
+	}
+	got, _ := os.ReadFile(trace)
+	if !strings.HasSuffix(string(got), "| 1 | set | `a.go:1` | old |\n| 2 | hit | `a.go:1` | new |\n") {
+		t.Errorf("10_trace.md =\n%s", got)
+	}
+}
+
+func TestReportHypothesisStatus(t *testing.T) {
+	dir := t.TempDir()
+	runCommands(t, [][]string{
+		{"report-init", "-pkg", "example", dir},
+		{"report-hypothesis", "-loc", "pipeline.go:27", "-expected", "start < end", "-actual", "start > end", dir},
+		{"report-hypothesis", "-id", "H2", "-loc", "pipeline.go:30", "-expected", "end = 16", "-actual", "?", dir},
+		{"report-evidence", "-loc", "pipeline.go:27", "-locals", "start = 12", dir},
+		{"report-evidence", "-loc", "pipeline.go:30", "-locals", "end = 15", dir},
+		{"report-hypothesis", "-id", "h2", "-actual", "end = 15", dir},
+		{"report-hypothesis-status", "-id", "H1", "-status", "refuted", "-evidence", "E1", dir},
+		{"report-hypothesis-status", "-id", "H2", "-status", "confirmed", "-evidence", "pipeline.go:30,1", dir},
+	})
+	for _, args := range [][]string{
+		{"-id", "H2", "-status", "confirmed", dir},
+		{"-id", "H2", "-status", "refuted", "-evidence", "E3", dir},
+		{"-id", "H3", "-status", "open", dir},
+		{"-id", "H1", "-status", "maybe", dir},
+	} {
+		if err := dispatch("report-hypothesis-status", args); err == nil {
+			t.Errorf("report-hypothesis-status %q succeeded", args)
+		}
+	}
+
+	m, _, err := loadReport(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Hypotheses) != 2 {
+		t.Fatalf("hypotheses = %+v", m.Hypotheses)
+	}
+	h2 := m.Hypotheses[1]
+	if h2.ID != "H2" || h2.Actual != "end = 15" || h2.Expected != "end = 16" || h2.Status != hypConfirmed ||
+		len(h2.Evidence) != 2 || h2.Evidence[0] != 2 || h2.Evidence[1] != 1 {
+		t.Errorf("H2 = %+v", h2)
+	}
+
+	md, _, err := readReportMarkdown(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"| H1 | `pipeline.go:27` | start < end | start > end | [refuted]{.badge .refuted} [E1](#evidence-1) |",
+		"| H2 | `pipeline.go:30` | end = 16 | end = 15 | [confirmed]{.badge .confirmed} [E2](#evidence-2), [E1](#evidence-1) |",
+		"### E2 — pipeline.go:30 {#evidence-2}",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("missing %q in:\n%s", want, md)
+		}
+	}
+	tex := string(renderLatex(md))
+	for _, want := range []string{
+		`\statusbadge{bad}{refuted} \hyperref[evidence-1]{E1}`,
+		`\subsubsection{E2 — pipeline.go:30}\label{evidence-2}`,
+	} {
+		if !strings.Contains(tex, want) {
+			t.Errorf("missing %q in:\n%s", want, tex)
+		}
+	}
+	body, _ := renderHTML(md)
+	for _, want := range []string{
+		`<span class="badge confirmed">confirmed</span> <a href="#evidence-2">E2</a>`,
+		`<h4 id="evidence-2">`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %q in:\n%s", want, body)
+		}
+	}
+}
+
+func TestReportIterations(t *testing.T) {
+	dir := t.TempDir()
+	runCommands(t, [][]string{
+		{"report-init", "-pkg", "example", dir},
+		{"report-trace-row", "-action", "set", "-loc", "pipeline.go:27", "-reason", "entry", dir},
+		{"report-iteration-start", "-label", "clamp end", dir},
+		{"report-trace-row", "-action", "hit", "-loc", "pipeline.go:30", "-reason", "end = 15", dir},
+		{"report-evidence", "-loc", "pipeline.go:30", "-locals", "end = 15", dir},
+		{"report-fix", "-text", "Use len(data).", "-diff", "-a\n+b", "-rejected", "-why", "still off by one", dir},
+		{"report-iteration-start", dir},
+		{"report-trace-row", "-action", "hit", "-loc", "pipeline.go:30", "-reason", "end = 16", dir},
+		{"report-trace-row", "-action", "verify", "-loc", "pipeline.go:30", "-reason", "tests pass", dir},
+		{"report-evidence", "-loc", "pipeline.go:30", "-locals", "end = 16", dir},
+		{"report-fix", "-text", "Clamp to len(data).", dir},
+	})
+	if err := dispatch("report-fix", []string{"-text", "x", "-rejected", dir}); err == nil {
+		t.Error("report-fix -rejected without -why succeeded")
+	}
+	if err := dispatch("report-iteration-start", []string{t.TempDir()}); err == nil {
+		t.Error("report-iteration-start succeeded without report.json")
+	}
+
+	m, _, err := loadReport(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frags := m.fragments()
+	wantTrace := traceHeaderMD + "| 1 | set | `pipeline.go:27` | entry |\n" +
+		"\n### Iteration 1: clamp end\n\n" + traceTableMD + "| 2 | hit | `pipeline.go:30` | end = 15 |\n" +
+		"\n### Iteration 2\n\n" + traceTableMD + "| 3 | hit | `pipeline.go:30` | end = 16 |\n| 4 | verify | `pipeline.go:30` | tests pass |\n"
+	if got := frags[reportTraceFile]; got != wantTrace {
+		t.Errorf("trace =\n%s\nwant\n%s", got, wantTrace)
+	}
+	evid := frags[reportEvidFile]
+	if i, j := strings.Index(evid, "### Iteration 1: clamp end\n\n### E1"), strings.Index(evid, "### Iteration 2\n\n### E2"); i < 0 || j < i {
+		t.Errorf("evidence not grouped by iteration:\n%s", evid)
+	}
+	conc := frags[reportConcFile]
+	for _, want := range []string{
+		"| 1 | clamp end | 2 | [E1](#evidence-1) | [rejected]{.badge .refuted} still off by one |\n" +
+			"| 2 |  | 3–4 | [E2](#evidence-2) | [applied]{.badge .confirmed} |\n",
+		"## Rejected Fix (Iteration 1: clamp end)\n\nUse len(data).\n\n**Why it failed:** still off by one\n\n```diff\n-a\n+b\n```\n",
+		"## Fix Applied\n\nClamp to len(data).\n",
+	} {
+		if !strings.Contains(conc, want) {
+			t.Errorf("missing %q in conclusion:\n%s", want, conc)
+		}
+	}
+
+	problems, err := runReportCheck(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range problems {
+		if strings.Contains(p, "trace row") || strings.Contains(p, "no runtime output") || strings.Contains(p, "Fix Applied") {
+			t.Errorf("report-check: %s", p)
+		}
+	}
+}
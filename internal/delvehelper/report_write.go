@@ -1,16 +1,19 @@
-// Report writing commands: report-init, report-hypothesis, report-trace-row,
-// report-evidence, report-root-cause, report-fix, report-verification.
+// Report writing commands: report-init, report-hypothesis,
+// report-hypothesis-status, report-trace-row, report-evidence,
+// report-iteration-start, report-root-cause, report-fix, report-verification.
 //
 // The debug report is split across numbered .md files in the artifact dir so
 // each section can be appended independently without collision:
 //
-//   00_report.md    – title + hypothesis
+//   00_report.md    – title + hypotheses
+//   05_failing_tests.md – failing tests and suggested breakpoints (written by triage)
 //   10_trace.md     – debugging trace table (rows appended incrementally)
 //   20_evidence.md  – breakpoint evidence blocks (appended per stop)
 //   90_conclusion.md – root cause + fix + post-fix verification
 //
 // report-build concatenates all .md files in sorted order, so the numbered
-// scheme guarantees the correct section sequence in the final PDF.
+// scheme guarantees the correct section sequence in the final PDF. The
+// fragments above are rendered from report.json (see report_model.go).
 //
 // Agents MUST use these commands to manipulate the report; never write or
 // edit report files directly.
@@ -21,6 +24,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -71,15 +75,8 @@ func readSourceContext(file string, line, ctx int) ([]string, int, error) {
 }
 
 func fmtSourceBlock(lines []string, firstLine, highlightLine int) string {
-	var sb strings.Builder
-	fmt.Fprintf(&sb, "```go {highlightlines=%d firstnumber=%d highlightcolor=yellow!40}\n",
-		highlightLine, firstLine)
-	for _, l := range lines {
-		sb.WriteString(l)
-		sb.WriteString("\n")
-	}
-	sb.WriteString("```\n")
-	return sb.String()
+	info := fmt.Sprintf("go {highlightlines=%d firstnumber=%d highlightcolor=yellow!40}", highlightLine, firstLine)
+	return mdCodeBlock(info, strings.Join(lines, "\n"))
 }
 
 // cmdReportInit creates the artifact dir, copies tex/lua templates, and
@@ -112,17 +109,35 @@ func cmdReportInit(args []string) error {
 			return err
 		}
 	}
-	header := fmt.Sprintf("# Debug Report — %s — %s\n\n", p, d)
-	if err := os.WriteFile(rfile(dir, reportMainFile), []byte(header), 0644); err != nil {
-		return fmt.Errorf("write %s: %w", reportMainFile, err)
+	m, ok, err := loadReport(dir)
+	if err != nil {
+		return err
+	}
+	if !ok && hasLegacyFragments(dir) {
+		// Keep appending to the existing fragments rather than replacing them
+		// with an empty model.
+		header := fmt.Sprintf("# Debug Report — %s — %s\n\n", p, d)
+		if err := os.WriteFile(rfile(dir, reportMainFile), []byte(header), 0644); err != nil {
+			return fmt.Errorf("write %s: %w", reportMainFile, err)
+		}
+	} else {
+		if !ok {
+			m = &reportModel{Version: reportModelVersion}
+		}
+		m.Package, m.Date = p, d
+		if err := saveReport(dir, m); err != nil {
+			return err
+		}
 	}
 	fmt.Printf("initialized %s (pkg=%s date=%s)\n", dir, p, d)
 	return nil
 }
 
-// cmdReportHypothesis appends the Hypothesis section to 00_report.md.
+// cmdReportHypothesis adds a hypothesis to 00_report.md, or refines the one
+// with the same -id (only the flags given are changed).
 func cmdReportHypothesis(args []string) error {
 	fs := flag.NewFlagSet("report-hypothesis", flag.ContinueOnError)
+	id := fs.String("id", "", "hypothesis id, e.g. H2 (default: the next H<n>)")
 	loc := fs.String("loc", "file:line", "suspected location (file:line or func name)")
 	expected := fs.String("expected", "<what should happen>", "expected behaviour")
 	actual := fs.String("actual", "<what was observed>", "actual observed behaviour")
@@ -130,17 +145,89 @@ func cmdReportHypothesis(args []string) error {
 		return err
 	}
 	if fs.NArg() != 1 {
-		return fmt.Errorf("usage: report-hypothesis -loc LOC -expected TEXT -actual TEXT <dbgdir>")
+		return fmt.Errorf("usage: report-hypothesis [-id ID] -loc LOC -expected TEXT -actual TEXT <dbgdir>")
+	}
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	h := reportHypothesis{ID: *id, Loc: *loc, Expected: *expected, Actual: *actual}
+	verb := "appended"
+	err := updateReport(fs.Arg(0), func(m *reportModel) {
+		if old := m.hypothesis(h.ID); old != nil {
+			if set["loc"] {
+				old.Loc = h.Loc
+			}
+			if set["expected"] {
+				old.Expected = h.Expected
+			}
+			if set["actual"] {
+				old.Actual = h.Actual
+			}
+			h, verb = *old, "refined"
+			return
+		}
+		if h.ID == "" {
+			h.ID = m.nextHypothesisID()
+		}
+		h.Seq = m.next()
+		m.Hypotheses = append(m.Hypotheses, h)
+	}, reportMainFile, "\n"+h.markdown())
+	if err != nil {
+		return err
+	}
+	fmt.Println(strings.TrimSpace(verb + " hypothesis " + h.ID))
+	return nil
+}
+
+// cmdReportHypothesisStatus records whether a hypothesis was confirmed or
+// refuted and by which evidence blocks. It needs the report model.
+func cmdReportHypothesisStatus(args []string) error {
+	fs := flag.NewFlagSet("report-hypothesis-status", flag.ContinueOnError)
+	id := fs.String("id", "", "hypothesis id (H1, H2, …)")
+	status := fs.String("status", "", "confirmed | refuted | open")
+	evid := fs.String("evidence", "", "evidence blocks behind the status, comma-separated: E2, 2 or a location label")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || *id == "" || *status == "" {
+		return fmt.Errorf("usage: report-hypothesis-status -id ID -status confirmed|refuted|open [-evidence REF[,REF]] <dbgdir>")
+	}
+	switch *status {
+	case hypConfirmed, hypRefuted:
+		if *evid == "" {
+			return fmt.Errorf("-status %s needs -evidence: cite the evidence block that shows it", *status)
+		}
+	case hypOpen:
+	default:
+		return fmt.Errorf("invalid -status %q (want confirmed, refuted or open)", *status)
 	}
 	dir := fs.Arg(0)
-	section := fmt.Sprintf(
-		"## Hypothesis\n\nSuspected location: `%s`\n\nExpected: %s\n\nActual: %s\n",
-		*loc, *expected, *actual,
-	)
-	if err := appendToFile(rfile(dir, reportMainFile), "\n"+section); err != nil {
+	m, ok, err := loadReport(dir)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%s has no %s (it was written by an older delve-helper); hypothesis status needs the report model", dir, reportModelFile)
+	}
+	h := m.hypothesis(*id)
+	if h == nil {
+		return fmt.Errorf("no hypothesis %s in %s", *id, rfile(dir, reportModelFile))
+	}
+	var refs []int
+	for _, ref := range strings.Split(*evid, ",") {
+		if ref = strings.TrimSpace(ref); ref == "" {
+			continue
+		}
+		n, err := m.evidenceRef(ref)
+		if err != nil {
+			return err
+		}
+		refs = append(refs, n)
+	}
+	h.Status, h.Evidence = *status, refs
+	if err := saveReport(dir, m); err != nil {
 		return err
 	}
-	fmt.Println("appended hypothesis")
+	fmt.Printf("hypothesis %s %s\n", h.ID, *status)
 	return nil
 }
 
@@ -148,7 +235,7 @@ func cmdReportHypothesis(args []string) error {
 // Creates the file with section and table header on the first call.
 func cmdReportTraceRow(args []string) error {
 	fs := flag.NewFlagSet("report-trace-row", flag.ContinueOnError)
-	n := fs.Int("n", 0, "row number")
+	n := fs.Int("n", 0, "row number (default: next after the last row)")
 	action := fs.String("action", "", "action: set | hit | clear | next | step")
 	loc := fs.String("loc", "", "location (file:line or description)")
 	reason := fs.String("reason", "", "one-line reasoning")
@@ -156,22 +243,132 @@ func cmdReportTraceRow(args []string) error {
 		return err
 	}
 	if fs.NArg() != 1 {
-		return fmt.Errorf("usage: report-trace-row -n N -action ACTION -loc LOC -reason REASON <dbgdir>")
+		return fmt.Errorf("usage: report-trace-row [-n N] -action ACTION -loc LOC -reason REASON <dbgdir>")
 	}
 	dir := fs.Arg(0)
-	path := rfile(dir, reportTraceFile)
-	row := fmt.Sprintf("| %d | %s | `%s` | %s |\n", *n, *action, *loc, *reason)
-	if !fileContains(path, "## Debugging Trace") {
-		header := "## Debugging Trace\n\n| # | Action | Location | Reasoning |\n| - | ------ | -------- | --------- |\n"
-		row = header + row
+	if *n == 0 {
+		*n = nextTraceRow(dir)
 	}
-	if err := appendToFile(path, row); err != nil {
+	if err := appendTraceRow(dir, *n, *action, *loc, *reason); err != nil {
 		return err
 	}
 	fmt.Printf("appended trace row %d (%s)\n", *n, *action)
 	return nil
 }
 
+// appendTraceRow appends one row to 10_trace.md, writing the section and
+// table header first if the file does not have them yet.
+func appendTraceRow(dir string, n int, action, loc, reason string) error {
+	r := reportTraceRow{N: n, Action: action, Loc: loc, Reason: reason}
+	legacy := r.markdown()
+	if !fileContains(rfile(dir, reportTraceFile), "## Debugging Trace") {
+		legacy = traceHeaderMD + legacy
+	}
+	return updateReport(dir, func(m *reportModel) {
+		r.Seq = m.next()
+		m.Trace = append(m.Trace, r)
+	}, reportTraceFile, legacy)
+}
+
+// cmdReportAnnotate sets the Reasoning column of trace row n, typically one
+// appended automatically by DLV_AUTO_TRACE.
+func cmdReportAnnotate(args []string) error {
+	fs := flag.NewFlagSet("report-annotate", flag.ContinueOnError)
+	reason := fs.String("reason", "", "one-line reasoning for the row")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	rest := fs.Args()
+	// Allow the flag after the row number too: report-annotate 3 -reason "...".
+	if len(rest) > 1 {
+		if err := fs.Parse(rest[1:]); err != nil {
+			return err
+		}
+		rest = append(rest[:1:1], fs.Args()...)
+	}
+	if len(rest) < 1 || len(rest) > 2 || *reason == "" {
+		return fmt.Errorf("usage: report-annotate <n> -reason REASON [dbgdir] (default dbgdir: $DBG_DIR)")
+	}
+	n, err := strconv.Atoi(rest[0])
+	if err != nil {
+		return fmt.Errorf("invalid row number %q", rest[0])
+	}
+	dir := os.Getenv("DBG_DIR")
+	if len(rest) == 2 {
+		dir = rest[1]
+	}
+	if dir == "" {
+		return fmt.Errorf("no artifact dir: pass it after the row number or set DBG_DIR")
+	}
+	if m, ok, err := loadReport(dir); err != nil {
+		return err
+	} else if ok {
+		found := false
+		for i := range m.Trace {
+			if m.Trace[i].N == n {
+				m.Trace[i].Reason = *reason
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("no trace row %d in %s", n, rfile(dir, reportModelFile))
+		}
+		if err := saveReport(dir, m); err != nil {
+			return err
+		}
+		fmt.Printf("annotated trace row %d\n", n)
+		return nil
+	}
+	path := rfile(dir, reportTraceFile)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", reportTraceFile, err)
+	}
+	lines := strings.Split(string(b), "\n")
+	found := false
+	for i, line := range lines {
+		var row int
+		if _, err := fmt.Sscanf(line, "| %d |", &row); err != nil || row != n {
+			continue
+		}
+		// | n | action | `loc` | reasoning |
+		cells := splitTableRow(line)
+		if len(cells) != 4 {
+			return fmt.Errorf("trace row %d is not in the expected format: %s", n, line)
+		}
+		lines[i] = fmt.Sprintf("| %s | %s | %s | %s |", cells[0], mdCell(cells[1]), mdCell(cells[2]), mdCell(*reason))
+		found = true
+	}
+	if !found {
+		return fmt.Errorf("no trace row %d in %s", n, path)
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		return fmt.Errorf("write %s: %w", reportTraceFile, err)
+	}
+	fmt.Printf("annotated trace row %d\n", n)
+	return nil
+}
+
+// nextTraceRow returns the number after the highest row in dir's Debugging
+// Trace table, or 1 when dir is empty or has no trace yet.
+func nextTraceRow(dir string) int {
+	if dir == "" {
+		return 1
+	}
+	b, err := os.ReadFile(rfile(dir, reportTraceFile))
+	if err != nil {
+		return 1
+	}
+	last := 0
+	for _, line := range strings.Split(string(b), "\n") {
+		var n int
+		if _, err := fmt.Sscanf(line, "| %d |", &n); err == nil && n > last {
+			last = n
+		}
+	}
+	return last + 1
+}
+
 // cmdReportEvidence appends one breakpoint evidence block to 20_evidence.md.
 func cmdReportEvidence(args []string) error {
 	fs := flag.NewFlagSet("report-evidence", flag.ContinueOnError)
@@ -184,58 +381,83 @@ func cmdReportEvidence(args []string) error {
 	stackOut := fs.String("stack", "", "output of: delve-helper stack")
 	printExpr := fs.String("print-expr", "", "expression passed to delve-helper print")
 	printVal := fs.String("print-val", "", "output of: delve-helper print <expr>")
+	outputLines := fs.Int("output", 0, "attach the last N lines of target output (requires start -redirect)")
 	obs := fs.String("obs", "", "one-sentence observation (what was found)")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
 	if fs.NArg() != 1 {
 		return fmt.Errorf("usage: report-evidence -loc LOC [-src-file F -highlight N] " +
-			"[-args A] [-locals L] [-stack S] [-print-expr E -print-val V] [-obs O] <dbgdir>")
-	}
-	dir := fs.Arg(0)
-	path := rfile(dir, reportEvidFile)
-
-	var sb strings.Builder
-	if !fileContains(path, "## Breakpoints & Evidence") {
-		sb.WriteString("## Breakpoints & Evidence\n")
+			"[-args A] [-locals L] [-stack S] [-print-expr E -print-val V] [-output N] [-obs O] <dbgdir>")
 	}
-	sb.WriteString(fmt.Sprintf("\n### %s\n\n", *loc))
-
-	if *srcFile != "" && *highlight > 0 {
-		lines, firstLine, err := readSourceContext(*srcFile, *highlight, *ctx)
-		if err == nil {
-			sb.WriteString("**Source context:**\n\n")
-			sb.WriteString(fmtSourceBlock(lines, firstLine, *highlight))
-			sb.WriteString("\n")
-		}
+	ev := evidence{
+		Loc: *loc, SrcFile: *srcFile, Highlight: *highlight, Ctx: *ctx,
+		Args: *argsOut, Locals: *localsOut, Stack: *stackOut,
+		OutputLines: *outputLines, Obs: *obs,
 	}
-	fmtBlock := func(label, text string) {
-		if text == "" {
-			return
-		}
-		sb.WriteString(fmt.Sprintf("**%s:**\n\n```text\n%s\n```\n\n",
-			label, strings.TrimRight(text, "\n")))
-	}
-	fmtBlock("Args", *argsOut)
-	fmtBlock("Locals", *localsOut)
-	fmtBlock("Stack", *stackOut)
 	if *printExpr != "" && *printVal != "" {
-		sb.WriteString(fmt.Sprintf("**Print `%s`:**\n\n```text\n%s\n```\n\n",
-			*printExpr, strings.TrimRight(*printVal, "\n")))
-	} else if *printVal != "" {
-		fmtBlock("Print", *printVal)
-	}
-	if *obs != "" {
-		sb.WriteString(fmt.Sprintf("**Observation:** %s\n", *obs))
+		ev.Prints = []evidencePrint{{Expr: *printExpr, Val: *printVal}}
+	} else {
+		ev.PrintVal = *printVal
 	}
-
-	if err := appendToFile(path, sb.String()); err != nil {
+	ref, err := writeEvidence(fs.Arg(0), ev)
+	if err != nil {
 		return err
 	}
-	fmt.Printf("appended evidence for %s\n", *loc)
+	fmt.Printf("appended evidence %sfor %s\n", ref, *loc)
 	return nil
 }
 
+// evidence is one breakpoint evidence block, filled from report-evidence
+// flags or captured directly by snapshot.
+type evidence struct {
+	Loc         string
+	SrcFile     string
+	Highlight   int
+	Ctx         int
+	Args        string
+	Locals      string
+	Stack       string
+	Prints      []evidencePrint
+	PrintVal    string // print output without a known expression
+	OutputLines int
+	Obs         string
+}
+
+type evidencePrint struct {
+	Expr string `json:"expr"`
+	Val  string `json:"value"`
+}
+
+// writeEvidence adds ev to the report in dir, reading the source context
+// and program output now. It returns the block's reference followed by a
+// space ("E2 "), or "" in an artifact dir without a model.
+func writeEvidence(dir string, ev evidence) (string, error) {
+	e := reportEvidence{
+		Loc: ev.Loc, Args: ev.Args, Locals: ev.Locals, Stack: ev.Stack,
+		Prints: ev.Prints, PrintVal: ev.PrintVal, Obs: ev.Obs,
+	}
+	if ev.SrcFile != "" && ev.Highlight > 0 {
+		if lines, first, err := readSourceContext(ev.SrcFile, ev.Highlight, ev.Ctx); err == nil {
+			e.Source = &reportSource{File: ev.SrcFile, First: first, Highlight: ev.Highlight, Lines: lines}
+		}
+	}
+	if ev.OutputLines > 0 {
+		e.Output = tailProgramOutput(ev.OutputLines)
+	}
+	legacy := e.markdown(0)
+	if !fileContains(rfile(dir, reportEvidFile), "## Breakpoints & Evidence") {
+		legacy = evidenceHeaderMD + legacy
+	}
+	ref := ""
+	err := updateReport(dir, func(m *reportModel) {
+		e.Seq = m.next()
+		m.Evidence = append(m.Evidence, e)
+		ref = fmt.Sprintf("E%d ", len(m.Evidence))
+	}, reportEvidFile, legacy)
+	return ref, err
+}
+
 // cmdReportRootCause appends the Root Cause section to 90_conclusion.md.
 func cmdReportRootCause(args []string) error {
 	fs := flag.NewFlagSet("report-root-cause", flag.ContinueOnError)
@@ -246,41 +468,78 @@ func cmdReportRootCause(args []string) error {
 	if fs.NArg() != 1 {
 		return fmt.Errorf("usage: report-root-cause -text TEXT <dbgdir>")
 	}
-	dir := fs.Arg(0)
-	section := fmt.Sprintf("## Root Cause\n\n%s\n", *text)
-	if err := appendToFile(rfile(dir, reportConcFile), "\n"+section); err != nil {
+	err := updateReport(fs.Arg(0), func(m *reportModel) {
+		m.RootCauses = append(m.RootCauses, reportText{Seq: m.next(), Text: *text})
+	}, reportConcFile, rootCauseMD(*text))
+	if err != nil {
 		return err
 	}
 	fmt.Println("appended root cause")
 	return nil
 }
 
-// cmdReportFix appends the Fix Applied section to 90_conclusion.md.
+// cmdReportFix appends the Fix Applied section to 90_conclusion.md, or with
+// -rejected a Rejected Fix section recording why the attempt failed.
 func cmdReportFix(args []string) error {
 	fs := flag.NewFlagSet("report-fix", flag.ContinueOnError)
 	text := fs.String("text", "", "fix description")
 	diff := fs.String("diff", "", "unified diff of the change (optional)")
+	rejected := fs.Bool("rejected", false, "the fix did not work (requires -why)")
+	why := fs.String("why", "", "why the rejected fix failed")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
 	if fs.NArg() != 1 {
-		return fmt.Errorf("usage: report-fix -text TEXT [-diff DIFF] <dbgdir>")
+		return fmt.Errorf("usage: report-fix -text TEXT [-diff DIFF] [-rejected -why WHY] <dbgdir>")
 	}
-	dir := fs.Arg(0)
-	var sb strings.Builder
-	sb.WriteString("\n## Fix Applied\n\n")
-	sb.WriteString(*text)
-	sb.WriteString("\n")
-	if *diff != "" {
-		sb.WriteString(fmt.Sprintf("\n```diff\n%s\n```\n", strings.TrimRight(*diff, "\n")))
-	}
-	if err := appendToFile(rfile(dir, reportConcFile), sb.String()); err != nil {
+	if *rejected != (*why != "") {
+		return fmt.Errorf("-rejected and -why go together: say why the fix failed")
+	}
+	f := reportFix{Text: *text, Diff: *diff, Rejected: *rejected, Why: *why}
+	err := updateReport(fs.Arg(0), func(m *reportModel) {
+		f.Seq = m.next()
+		m.Fixes = append(m.Fixes, f)
+	}, reportConcFile, f.markdown(""))
+	if err != nil {
 		return err
 	}
+	if f.Rejected {
+		fmt.Println("appended rejected fix")
+		return nil
+	}
 	fmt.Println("appended fix")
 	return nil
 }
 
+// cmdReportIterationStart starts the next iteration of the fix loop: trace
+// rows, evidence and fixes added from now on are grouped under it. It needs
+// the report model.
+func cmdReportIterationStart(args []string) error {
+	fs := flag.NewFlagSet("report-iteration-start", flag.ContinueOnError)
+	label := fs.String("label", "", "short label, e.g. the hypothesis or fix being tried")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: report-iteration-start [-label LABEL] <dbgdir>")
+	}
+	dir := fs.Arg(0)
+	m, ok, err := loadReport(dir)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%s has no %s (it was written by an older delve-helper); iterations need the report model", dir, reportModelFile)
+	}
+	it := reportIteration{Seq: m.next(), N: len(m.Iterations) + 1, Label: *label}
+	m.Iterations = append(m.Iterations, it)
+	if err := saveReport(dir, m); err != nil {
+		return err
+	}
+	fmt.Printf("started %s\n", it.title())
+	return nil
+}
+
 // cmdReportVerification appends the Post-fix Verification section to 90_conclusion.md.
 func cmdReportVerification(args []string) error {
 	fs := flag.NewFlagSet("report-verification", flag.ContinueOnError)
@@ -291,9 +550,10 @@ func cmdReportVerification(args []string) error {
 	if fs.NArg() != 1 {
 		return fmt.Errorf("usage: report-verification -text TEXT <dbgdir>")
 	}
-	dir := fs.Arg(0)
-	section := fmt.Sprintf("\n## Post-fix Verification\n\n%s\n", *text)
-	if err := appendToFile(rfile(dir, reportConcFile), section); err != nil {
+	err := updateReport(fs.Arg(0), func(m *reportModel) {
+		m.Verifications = append(m.Verifications, reportText{Seq: m.next(), Text: *text})
+	}, reportConcFile, verificationMD(*text))
+	if err != nil {
 		return err
 	}
 	fmt.Println("appended verification")
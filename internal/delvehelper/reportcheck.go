@@ -0,0 +1,280 @@
+// report-check: lint an artifact dir against the protocol's minimum report
+// (hypothesis, a breakpoint hit, evidence with args/locals/stack and a
+// successful print, root cause, fix, verification). report-build runs it.
+package delvehelper
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// evidenceNumberRE matches the "E2 — " that numbers evidence headings.
+var evidenceNumberRE = regexp.MustCompile(`^E\d+( — |$)`)
+
+// iterationHeadingRE matches the "Iteration 2" headings that group trace
+// rows and evidence.
+var iterationHeadingRE = regexp.MustCompile(`^Iteration \d+(:|$)`)
+
+// parseReport reads the report back from its Markdown. Seq follows document
+// order, so unlike report.json it says nothing about when items were added.
+func parseReport(md string) *reportModel {
+	m := &reportModel{}
+	section := ""
+	var ev *reportEvidence
+	label := ""
+	flushEvidence := func() {
+		if ev != nil {
+			m.Evidence = append(m.Evidence, *ev)
+			ev = nil
+		}
+	}
+	for _, b := range parseMarkdown(md) {
+		switch {
+		case b.Kind == mdHeading && b.Level <= 2:
+			flushEvidence()
+			section = strings.TrimSpace(b.Text)
+			label = ""
+			if strings.HasPrefix(section, "Hypothesis ") {
+				section = "Hypothesis" // "## Hypothesis H2"
+			}
+			switch section {
+			case "Hypothesis":
+				m.Hypotheses = append(m.Hypotheses, reportHypothesis{Seq: m.next()})
+			case "Root Cause":
+				m.RootCauses = append(m.RootCauses, reportText{Seq: m.next()})
+			case "Fix", "Fix Applied":
+				m.Fixes = append(m.Fixes, reportFix{Seq: m.next()})
+			case "Post-fix Verification":
+				m.Verifications = append(m.Verifications, reportText{Seq: m.next()})
+			}
+		case b.Kind == mdHeading && iterationHeadingRE.MatchString(b.Text):
+			flushEvidence()
+			label = ""
+		case b.Kind == mdHeading && section == "Breakpoints & Evidence":
+			flushEvidence()
+			ev = &reportEvidence{Seq: m.next(), Loc: evidenceNumberRE.ReplaceAllString(b.Text, "")}
+			label = ""
+		case b.Kind == mdTable && section == "Hypotheses":
+			for _, row := range b.Rows {
+				h := reportHypothesis{Seq: m.next()}
+				if len(row) >= 5 {
+					h.ID, h.Loc, h.Expected, h.Actual = row[0], strings.Trim(row[1], "` "), row[2], row[3]
+					for _, in := range parseInline(row[4]) {
+						if in.Kind == "span" {
+							h.Status = in.Text
+						}
+					}
+				}
+				m.Hypotheses = append(m.Hypotheses, h)
+			}
+		case b.Kind == mdTable && section == "Debugging Trace":
+			for _, row := range b.Rows {
+				r := reportTraceRow{Seq: m.next()}
+				cell := func(i int) string {
+					if i < len(row) {
+						return row[i]
+					}
+					return ""
+				}
+				r.N, _ = strconv.Atoi(cell(0))
+				r.Action, r.Loc, r.Reason = cell(1), strings.Trim(cell(2), "` "), cell(3)
+				m.Trace = append(m.Trace, r)
+			}
+		case ev != nil && b.Kind == mdParagraph && strings.HasPrefix(b.Text, "**Observation:**"):
+			ev.Obs = strings.TrimSpace(strings.TrimPrefix(b.Text, "**Observation:**"))
+		case ev != nil && b.Kind == mdParagraph && strings.HasPrefix(b.Text, "**") && strings.HasSuffix(b.Text, ":**"):
+			label = strings.TrimSuffix(strings.TrimPrefix(b.Text, "**"), ":**")
+		case ev != nil && b.Kind == mdCode:
+			switch {
+			case label == "Source context":
+				ev.Source = &reportSource{Lines: strings.Split(b.Text, "\n")}
+			case label == "Args":
+				ev.Args = b.Text
+			case label == "Locals":
+				ev.Locals = b.Text
+			case label == "Stack":
+				ev.Stack = b.Text
+			case label == "Print":
+				ev.PrintVal = b.Text
+			case label == "Program output":
+				ev.Output = b.Text
+			case strings.HasPrefix(label, "Print "):
+				expr := strings.Trim(strings.TrimPrefix(label, "Print "), "` ")
+				ev.Prints = append(ev.Prints, evidencePrint{Expr: expr, Val: b.Text})
+			}
+			label = ""
+		case b.Kind == mdParagraph:
+			addReportText(m, section, b.Text)
+		case b.Kind == mdCode && (section == "Fix" || section == "Fix Applied") && len(m.Fixes) > 0 && b.Lang == "diff":
+			m.Fixes[len(m.Fixes)-1].Diff = b.Text
+		}
+	}
+	flushEvidence()
+	return m
+}
+
+// addReportText adds a paragraph to the item of the current section.
+func addReportText(m *reportModel, section, text string) {
+	join := func(s *string) {
+		if *s != "" {
+			*s += "\n\n"
+		}
+		*s += text
+	}
+	switch section {
+	case "Hypothesis":
+		h := &m.Hypotheses[len(m.Hypotheses)-1]
+		switch {
+		case strings.HasPrefix(text, "Suspected location:"):
+			h.Loc = strings.Trim(strings.TrimPrefix(text, "Suspected location:"), "` ")
+		case strings.HasPrefix(text, "Expected:"):
+			h.Expected = strings.TrimSpace(strings.TrimPrefix(text, "Expected:"))
+		case strings.HasPrefix(text, "Actual:"):
+			h.Actual = strings.TrimSpace(strings.TrimPrefix(text, "Actual:"))
+		}
+	case "Root Cause":
+		join(&m.RootCauses[len(m.RootCauses)-1].Text)
+	case "Fix", "Fix Applied":
+		join(&m.Fixes[len(m.Fixes)-1].Text)
+	case "Post-fix Verification":
+		join(&m.Verifications[len(m.Verifications)-1].Text)
+	}
+}
+
+// printSucceeded reports whether a print output is a value rather than an
+// evaluation error.
+func printSucceeded(val string) bool {
+	val = strings.TrimSpace(val)
+	return val != "" && !strings.HasPrefix(val, "<error") &&
+		!strings.Contains(val, "could not find symbol") && !strings.HasPrefix(val, "delve-helper:")
+}
+
+// checkReport returns the problems that make the report incomplete. order
+// supplies the recording order (report.json) when known, else nil.
+func checkReport(m, order *reportModel) []string {
+	var problems []string
+	add := func(format string, a ...any) { problems = append(problems, fmt.Sprintf(format, a...)) }
+
+	if len(m.Hypotheses) == 0 {
+		add("missing Hypothesis section (report-hypothesis)")
+	}
+	for i, h := range m.Hypotheses {
+		if h.Loc == "file:line" || strings.HasPrefix(h.Expected, "<") || strings.HasPrefix(h.Actual, "<") {
+			add("hypothesis %d still has placeholder text", i+1)
+		}
+	}
+
+	if len(m.Trace) == 0 {
+		add("missing Debugging Trace (report-trace-row or DLV_AUTO_TRACE=1)")
+	}
+	hit := false
+	for i, r := range m.Trace {
+		if strings.HasPrefix(strings.ToLower(r.Action), "hit") {
+			hit = true
+		}
+		switch {
+		case r.N == 0:
+			add("trace row %d has no row number", i+1)
+		case i == 0 && r.N != 1:
+			add("trace starts at row %d instead of 1", r.N)
+		case i > 0 && r.N != m.Trace[i-1].N+1:
+			add("trace row %d follows row %d (rows must be numbered 1, 2, 3, … in order)", r.N, m.Trace[i-1].N)
+		}
+	}
+	if len(m.Trace) > 0 && !hit {
+		add("no trace row records a breakpoint hit (action \"hit\")")
+	}
+
+	if len(m.Evidence) == 0 {
+		add("missing Breakpoints & Evidence (snapshot -dbg or report-evidence)")
+	}
+	complete := false
+	for _, e := range m.Evidence {
+		printed := printSucceeded(e.PrintVal)
+		for _, p := range e.Prints {
+			printed = printed || printSucceeded(p.Val)
+		}
+		if e.Args == "" && e.Locals == "" && e.Stack == "" && !printed && e.Output == "" {
+			add("evidence block %q has no runtime output (args, locals, stack, print or program output)", e.Loc)
+		}
+		if e.Args != "" && e.Locals != "" && e.Stack != "" && printed {
+			complete = true
+		}
+	}
+	if len(m.Evidence) > 0 && !complete {
+		add("no evidence block has args, locals, stack and a successful print together")
+	}
+
+	if len(m.RootCauses) == 0 || strings.TrimSpace(m.RootCauses[0].Text) == "" {
+		add("missing Root Cause (report-root-cause)")
+	}
+	if len(m.Fixes) == 0 {
+		add("missing Fix Applied (report-fix)")
+	}
+	if len(m.Verifications) == 0 {
+		add("missing Post-fix Verification (report-verification)")
+	}
+
+	if order != nil && len(order.Fixes) > 0 {
+		first := order.Fixes[0].Seq
+		for _, f := range order.Fixes {
+			first = min(first, f.Seq)
+		}
+		before := true
+		for _, e := range order.Evidence {
+			if e.Seq < first {
+				before = false
+			}
+		}
+		if before {
+			add("a fix was recorded before any evidence (the protocol requires runtime evidence first)")
+		}
+	}
+	return problems
+}
+
+// runReportCheck checks the report in dir and returns its problems.
+func runReportCheck(dir string) ([]string, error) {
+	md, _, err := readReportMarkdown(dir)
+	if err != nil {
+		return nil, err
+	}
+	order, ok, err := loadReport(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		order = nil
+	}
+	return checkReport(parseReport(fixMarkdownTables(md)), order), nil
+}
+
+func cmdReportCheck(args []string) error {
+	fs := flag.NewFlagSet("report-check", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	dir := os.Getenv("DBG_DIR")
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+	if fs.NArg() > 1 || dir == "" {
+		return fmt.Errorf("usage: report-check [dbgdir] (default: $DBG_DIR)")
+	}
+	problems, err := runReportCheck(dir)
+	if err != nil {
+		return err
+	}
+	if len(problems) == 0 {
+		fmt.Printf("report in %s is complete\n", dir)
+		return nil
+	}
+	for _, p := range problems {
+		fmt.Println("  - " + p)
+	}
+	return fmt.Errorf("report in %s is incomplete: %d problem(s)", dir, len(problems))
+}
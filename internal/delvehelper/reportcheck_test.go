@@ -0,0 +1,40 @@
+package delvehelper
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReportCheck(t *testing.T) {
+	dir := t.TempDir()
+	runCommands(t, [][]string{
+		{"report-init", "-pkg", "example", dir},
+		{"report-hypothesis", "-loc", "pipeline.go:27", "-expected", "end = 16", "-actual", "end = 15", dir},
+		{"report-trace-row", "-action", "set", "-loc", "pipeline.go:27", "-reason", "clamp", dir},
+		{"report-fix", "-text", "Clamp to len(data).", dir},
+		{"report-trace-row", "-n", "4", "-action", "hit", "-loc", "pipeline.go:27", "-reason", "fired", dir},
+		{"report-evidence", "-loc", "pipeline.go:27", "-args", "size = 4", "-locals", "end = 16",
+			"-stack", "0 example.Window", "-print-expr", "end", "-print-val", "end = 16", dir},
+	})
+	problems, err := runReportCheck(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"trace row 4 follows row 1",
+		"missing Root Cause",
+		"missing Post-fix Verification",
+		"a fix was recorded before any evidence",
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("problems = %q, want %d", problems, len(want))
+	}
+	for i, w := range want {
+		if !strings.Contains(problems[i], w) {
+			t.Errorf("problem %d = %q, want it to contain %q", i, problems[i], w)
+		}
+	}
+	if err := dispatch("report-build", []string{dir}); err == nil {
+		t.Error("report-build accepted an incomplete report without -force")
+	}
+}
@@ -3,6 +3,7 @@ package delvehelper
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -10,30 +11,129 @@ import (
 	"github.com/go-delve/delve/service/api"
 )
 
-// getDlvDir returns the directory for .dlv/addr and .dlv/pid, relative to cwd.
+// getDlvBaseDir returns the .dlv directory, relative to cwd.
 // If DBG_DIR is set (e.g. .debug_2025-02-28), .dlv is created inside it so the
 // project root stays clean. Otherwise .dlv is created in the current directory.
-func getDlvDir() string {
+func getDlvBaseDir() string {
 	if d := os.Getenv("DBG_DIR"); d != "" {
 		return filepath.Join(d, ".dlv")
 	}
 	return ".dlv"
 }
 
+// getDlvDir returns the directory for addr and pid of the current session:
+// .dlv/ for the default session, .dlv/<name>/ when DLV_SESSION (or -session) names one.
+func getDlvDir() string {
+	if name := os.Getenv("DLV_SESSION"); name != "" {
+		return filepath.Join(getDlvBaseDir(), name)
+	}
+	return getDlvBaseDir()
+}
+
+// extractSession removes a leading "-session NAME" (or --session, or the
+// =NAME forms) from args and returns the name, or "" if absent. Only the
+// flag spellings match, so "print session" keeps its argument.
+func extractSession(args []string) (string, []string, error) {
+	if len(args) == 0 {
+		return "", args, nil
+	}
+	a := args[0]
+	if strings.HasPrefix(a, "--") {
+		a = a[1:]
+	}
+	switch {
+	case a == "-session":
+		if len(args) < 2 {
+			return "", nil, fmt.Errorf("-session requires a name")
+		}
+		return args[1], args[2:], nil
+	case strings.HasPrefix(a, "-session="):
+		return strings.TrimPrefix(a, "-session="), args[1:], nil
+	}
+	return "", args, nil
+}
+
+// validSessionName rejects names that would escape .dlv/ or collide with its files.
+func validSessionName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid session name %q", name)
+	}
+	switch {
+	case name == "addr", name == "pid", name == "target", name == "rpc.log",
+		name == substitutePathFile, name == displayFile, name == historyFile, name == transcriptFile,
+		strings.HasPrefix(name, "daemon."), strings.HasPrefix(name, "target."), strings.HasPrefix(name, "output."):
+		return fmt.Errorf("invalid session name %q (reserved file name in .dlv/)", name)
+	}
+	return nil
+}
+
+// stdout receives the output of session commands. The daemon swaps it for a
+// per-request buffer so the output can be sent back to the forwarding CLI.
+var stdout io.Writer = os.Stdout
+
 // Run dispatches CLI arguments to the appropriate command handler.
 func Run(argv []string) error {
-	if len(argv) < 2 {
+	// -session may come before the command or right after it.
+	name, rest, err := extractSession(argv[1:])
+	if err != nil {
+		return err
+	}
+	if len(rest) < 1 {
 		printUsage()
 		return nil
 	}
-	cmd := strings.ToLower(argv[1])
-	args := argv[2:]
+	cmd := strings.ToLower(rest[0])
+	args := rest[1:]
+	if name == "" {
+		if name, args, err = extractSession(args); err != nil {
+			return err
+		}
+	}
+	if name != "" {
+		os.Setenv("DLV_SESSION", name)
+	}
+	if name := os.Getenv("DLV_SESSION"); name != "" {
+		if err := validSessionName(name); err != nil {
+			return err
+		}
+	}
 
+	if cmd != "transcript" && cmd != "daemon" {
+		if path := transcriptPath(); path != "" {
+			return recordTranscript(path, cmd, args)
+		}
+	}
+	return dispatch(cmd, args)
+}
+
+// dispatch runs one command after the session has been selected.
+func dispatch(cmd string, args []string) error {
 	if cmd == "start" {
 		return cmdStart(args)
 	}
+	if cmd == "build" {
+		return cmdBuild(args)
+	}
+	if cmd == "triage" {
+		return cmdTriage(args)
+	}
+	if cmd == "from-panic" {
+		return cmdFromPanic(args)
+	}
+	if cmd == "diff-locals" {
+		return cmdDiffLocals(args)
+	}
+	if cmd == "undisplay" {
+		return cmdUndisplay(args)
+	}
 	if cmd == "stop" {
-		return cmdStop()
+		return cmdStop(args)
+	}
+	if cmd == "connect" {
+		return cmdConnect(args)
+	}
+	if cmd == "sessions" {
+		return cmdSessions()
 	}
 	if cmd == "install-templates" {
 		return cmdInstallTemplates()
@@ -41,18 +141,30 @@ func Run(argv []string) error {
 	if cmd == "report-build" {
 		return cmdReportBuild(args)
 	}
+	if cmd == "report-check" {
+		return cmdReportCheck(args)
+	}
 	if cmd == "report-init" {
 		return cmdReportInit(args)
 	}
 	if cmd == "report-hypothesis" {
 		return cmdReportHypothesis(args)
 	}
+	if cmd == "report-hypothesis-status" {
+		return cmdReportHypothesisStatus(args)
+	}
 	if cmd == "report-trace-row" {
 		return cmdReportTraceRow(args)
 	}
+	if cmd == "report-annotate" {
+		return cmdReportAnnotate(args)
+	}
 	if cmd == "report-evidence" {
 		return cmdReportEvidence(args)
 	}
+	if cmd == "report-iteration-start" {
+		return cmdReportIterationStart(args)
+	}
 	if cmd == "report-root-cause" {
 		return cmdReportRootCause(args)
 	}
@@ -62,73 +174,163 @@ func Run(argv []string) error {
 	if cmd == "report-verification" {
 		return cmdReportVerification(args)
 	}
+	if cmd == "output" {
+		return cmdOutput(args)
+	}
+	if cmd == "daemon" {
+		return cmdDaemon(args)
+	}
+	if cmd == "transcript" {
+		return cmdTranscript(args)
+	}
+	if cmd == "script" {
+		return cmdScript(args)
+	}
+	if _, ok := sessionCommands[cmd]; !ok {
+		printUsage()
+		return fmt.Errorf("unknown command: %s", cmd)
+	}
+	if forwarded, err := forwardToDaemon(cmd, args); forwarded {
+		return err
+	}
 	client, err := newClient()
 	if err != nil {
 		return err
 	}
 	defer client.Disconnect(false)
+	return execSession(client, cmd, args)
+}
 
-	state, err := client.GetState()
-	if err != nil {
-		// Fix #3: when the tracee has already exited, GetState returns an error
-		// like "Process N has exited with status M". Treat this as informational
-		// (exit 0) rather than a hard failure so the agent sees a clean message.
-		if strings.Contains(err.Error(), "has exited with status") {
-			fmt.Println(err)
-			return nil
-		}
-		return err
-	}
+// sessionFunc is the signature of commands that run against a live Delve session.
+type sessionFunc func(client *loggingClient, state *api.DebuggerState, args []string) error
 
-	switch cmd {
-	case "state":
+// sessionCommands maps command names (and their aliases) to handlers that need
+// an RPC connection. Both the CLI and the daemon dispatch through this table.
+var sessionCommands = map[string]sessionFunc{
+	"state": func(_ *loggingClient, state *api.DebuggerState, _ []string) error {
 		return printState(state)
-	case "break":
-		return cmdBreak(client, state, args)
-	case "breakpoints", "bp":
+	},
+	"break": cmdBreak,
+	"breakpoints": func(client *loggingClient, _ *api.DebuggerState, _ []string) error {
 		return cmdBreakpoints(client)
-	case "clear":
+	},
+	"clear": func(client *loggingClient, _ *api.DebuggerState, args []string) error {
 		return cmdClear(client, args)
-	case "continue", "c":
-		return cmdContinue(client)
-	case "next", "n":
+	},
+	"continue": func(client *loggingClient, _ *api.DebuggerState, args []string) error {
+		return cmdContinue(client, args)
+	},
+	"halt": func(client *loggingClient, state *api.DebuggerState, _ []string) error {
+		return cmdHalt(client, state)
+	},
+	"wait": func(client *loggingClient, _ *api.DebuggerState, args []string) error {
+		return cmdWait(client, args)
+	},
+	"next": func(client *loggingClient, _ *api.DebuggerState, _ []string) error {
 		return cmdStep(client, api.Next)
-	case "step", "s":
+	},
+	"step": func(client *loggingClient, _ *api.DebuggerState, _ []string) error {
 		return cmdStep(client, api.Step)
-	case "stepout", "so":
+	},
+	"stepout": func(client *loggingClient, _ *api.DebuggerState, _ []string) error {
 		return cmdStep(client, api.StepOut)
-	case "print", "p":
-		return cmdPrint(client, state, args)
-	case "locals":
+	},
+	"print": cmdPrint,
+	"locals": func(client *loggingClient, state *api.DebuggerState, _ []string) error {
 		return cmdLocals(client, state)
-	case "args":
+	},
+	"args": func(client *loggingClient, state *api.DebuggerState, _ []string) error {
 		return cmdArgs(client, state)
-	case "stack", "bt":
+	},
+	"stack": func(client *loggingClient, state *api.DebuggerState, _ []string) error {
 		return cmdStack(client, state)
-	case "goroutines", "grs":
+	},
+	"goroutines": func(client *loggingClient, _ *api.DebuggerState, _ []string) error {
 		return cmdGoroutines(client)
-	default:
-		printUsage()
-		return fmt.Errorf("unknown command: %s", cmd)
+	},
+	"display":    cmdDisplay,
+	"snapshot":   cmdSnapshot,
+	"expect":     cmdExpect,
+	"expect-loc": cmdExpectLoc,
+	"restart": func(client *loggingClient, _ *api.DebuggerState, args []string) error {
+		return cmdRestart(client, args)
+	},
+}
+
+func init() {
+	for alias, name := range map[string]string{
+		"bp": "breakpoints", "c": "continue", "n": "next", "s": "step",
+		"so": "stepout", "p": "print", "bt": "stack", "grs": "goroutines",
+	} {
+		sessionCommands[alias] = sessionCommands[name]
 	}
 }
 
+// execSession fetches the current state over client and runs the session command.
+// The non-blocking state call keeps halt, wait and state usable while the
+// target is running after continue -async.
+func execSession(client *loggingClient, cmd string, args []string) error {
+	traceReason, args = takeReason(cmd, args)
+	defer func() { traceReason = "" }()
+	// restart is most useful once the target has exited, so it skips the exit check below.
+	if cmd == "restart" {
+		return sessionCommands[cmd](client, nil, args)
+	}
+	state, err := client.GetStateNonBlocking()
+	if err != nil {
+		// Fix #3: when the tracee has already exited, GetState returns an error
+		// like "Process N has exited with status M". Treat this as informational
+		// (exit 0) rather than a hard failure so the agent sees a clean message.
+		if strings.Contains(err.Error(), "has exited with status") {
+			fmt.Fprintln(stdout, err)
+			return nil
+		}
+		return err
+	}
+	return sessionCommands[cmd](client, state, args)
+}
+
 func printUsage() {
 	fmt.Fprintf(os.Stderr, `Usage: delve-helper <command> [args]
 
 Session lifecycle:
-  start [-test|-exec] [pkg|binary]  Start headless dlv. Writes addr and pid to DBG_DIR/.dlv/ if DBG_DIR is set, else .dlv/.
-  stop               Terminate the running Delve session (SIGTERM) and clean up .dlv/.
+  start [-test|-exec] [-redirect] [-stdin FILE] [-listen ADDR] [-startup-timeout D]
+        [-build-flags FLAGS] [-wd DIR] [-env KEY=VAL]... [-backend NAME] [-run TEST|-failing] [pkg|binary] [-- args]
+                     Start headless dlv. Writes addr and pid to DBG_DIR/.dlv/ if DBG_DIR is set, else .dlv/.
+                     dlv's stderr (including compiler errors) is kept in .dlv/dlv.log.
+                     -redirect sends target stdout/stderr to .dlv/target.out and .dlv/target.err;
+                     -stdin feeds FILE to the target via .dlv/target.in.
+                     -test -run TestA[/sub] debugs one test (anchored) and breaks at its entry;
+                     -test -failing runs go test -json first and picks the failing test.
+                     Program arguments go after --; with -test, start flags may also follow pkg.
+                     -exec warns when the binary is optimized, stripped or built with -trimpath.
+  build [-tags T] [-o PATH] [-no-start] [pkg] [-- args]
+                     Build pkg with -gcflags='all=-N -l' (no -trimpath) into a temp dir,
+                     check the binary, then start it with start -exec.
+  connect [-substitute-path from=to]... <host:port>
+                     Use a headless Delve server started elsewhere (remote host, container).
+                     -substitute-path maps the build-time source dir to the local one, e.g.
+                     /app=$PWD, so "break pipeline.go:32" resolves; also accepted by start.
+  stop [-all]        Terminate the running Delve session (SIGTERM) and clean up .dlv/.
+                     -all stops every session (default and named).
+  sessions           List sessions with target, PID, state and port.
   state              Print current debugger state.
+  daemon [-stop]     Keep one RPC connection open and serve commands over .dlv/daemon.sock.
+                     While it runs, session commands are forwarded to it transparently.
 
 Breakpoint & execution control:
   break <locspec> [if <cond>]  Set breakpoint (e.g. main.go:42, main.main, "main.go:55 if x==5").
   breakpoints        List all breakpoints.
   clear <id>         Clear breakpoint by ID.
-  continue           Resume execution until next stop.
+  continue [-timeout D] [-async]
+                     Resume execution until next stop. -timeout halts the target after D
+                     (e.g. 10s) and lists goroutines; -async returns immediately.
+  halt               Stop a running target (after continue -async).
+  wait [-timeout D]  Block until a running target stops, then print its state.
   next               Step over.
   step               Step into.
   stepout            Step out of current function.
+  restart [-rebuild] Restart the target from the beginning, keeping breakpoints.
 
 Inspection:
   print <expr>       Evaluate expression.
@@ -136,30 +338,86 @@ Inspection:
   args               Print function arguments.
   stack              Print stack trace.
   goroutines         List goroutines.
+  snapshot [-dbg DIR] [-print EXPR]... [-loc LABEL] [-ctx N] [-output N] [-obs TEXT]
+                     Print state, source context, args, locals, stack and each -print EXPR in
+                     one session; with -dbg also append them as an evidence block (20_evidence.md).
+  expect <expr> <op> <value>
+                     Fail (exit 1) with a got/want diff unless expr, evaluated at the current stop,
+                     compares to value; op is == != < <= > >= or contains (numbers compare numerically).
+  expect-loc <file:line>
+                     Fail unless the target is stopped at file:line (file may be a base name).
+  display [expr]     Add expr to the display list (.dlv/display.json), evaluated and printed
+                     after every stop of continue/next/step/stepout/halt/wait; no expr shows the list.
+  undisplay <n>... | -all
+                     Remove display expressions by number.
+  transcript [-md] [-to-script] [-file PATH]
+                     Print the commands recorded with DLV_TRANSCRIPT=1 (.dlv/transcript.jsonl):
+                     command, output and stop state after each; -to-script prints a script file.
+  script [-json] [-keep-going] <file|->
+                     Run delve-helper commands from a file, one per line (# comments, shell
+                     quoting), over one connection. "assert-output TEXT" fails unless the previous
+                     command printed TEXT. Stops at the first failure unless -keep-going.
+  diff-locals [-list] [-all] [stopA [stopB]]
+                     Compare values recorded by print/locals/args/display at two stops
+                     (.dlv/history.jsonl; default: the last two): + added, - removed, ~ old → new.
+  output [-follow] [-since-last] [-stream out|err|both]
+                     Print target output captured by start -redirect.
+
+Triage:
+  triage [-dbg DIR] [-run REGEXP] [pkg]
+                     Run go test -json, print each failure's file:line, panic stack and
+                     suggested breakpoints; write 05_failing_tests.md to -dbg (default $DBG_DIR).
+
+  from-panic [-C DIR] <file|->
+                     Parse a panic or GOTRACEBACK=all dump, map frames to the local module,
+                     and print suggested break commands and report-trace-row seeds.
 
 Report writing (use these; never edit report files directly):
   report-init [-pkg PKG] [-date DATE] <dir>
                      Create artifact dir, copy templates, init 00_report.md.
-  report-hypothesis -loc LOC -expected TEXT -actual TEXT <dir>
-                     Append Hypothesis section to 00_report.md.
-  report-trace-row -n N -action ACTION -loc LOC -reason REASON <dir>
-                     Append one row to Debugging Trace table (10_trace.md).
+  report-hypothesis [-id H2] -loc LOC -expected TEXT -actual TEXT <dir>
+                     Add a hypothesis (id defaults to the next H<n>); an existing -id is refined.
+  report-hypothesis-status -id H1 -status confirmed|refuted|open [-evidence E2[,E3]] <dir>
+                     Record what the evidence showed; -evidence (E<n>, n or a location) is
+                     required for confirmed and refuted and links the hypothesis to those blocks.
+  report-trace-row [-n N] -action ACTION -loc LOC -reason REASON <dir>
+                     Append one row to Debugging Trace table (10_trace.md); -n defaults to the next row.
+  report-annotate <n> -reason REASON [dir]
+                     Set the Reasoning of trace row n (dir defaults to $DBG_DIR).
   report-evidence -loc LOC [-src-file F -highlight N] [-args A] [-locals L]
-                  [-stack S] [-print-expr E -print-val V] [-obs O] <dir>
+                  [-stack S] [-print-expr E -print-val V] [-output N] [-obs O] <dir>
                      Append breakpoint evidence block (20_evidence.md).
+  report-iteration-start [-label LABEL] <dir>
+                     Start the next iteration of the fix loop: later trace rows, evidence and
+                     fixes are grouped under "Iteration N", summarized in the conclusion.
   report-root-cause -text TEXT <dir>
                      Append Root Cause section (90_conclusion.md).
-  report-fix -text TEXT [-diff DIFF] <dir>
-                     Append Fix Applied section (90_conclusion.md).
+  report-fix -text TEXT [-diff DIFF] [-rejected -why WHY] <dir>
+                     Append Fix Applied section (90_conclusion.md); -rejected records a failed
+                     attempt and why it failed.
   report-verification -text TEXT <dir>
                      Append Post-fix Verification section (90_conclusion.md).
-  report-build [-pkg pkg] [-date date] [-pdf] [-out path] [-v] <dir>
-                     Convert all .md files → LaTeX; -pdf compiles to PDF.
+  report-build [-pkg pkg] [-date date] [-renderer auto|native|pandoc] [-html] [-json] [-pdf] [-out path] [-force] [-v] <dir>
+                     Convert all .md files → LaTeX; -pdf compiles to PDF. -renderer=auto (default)
+                     uses pandoc when installed and the built-in Go renderer otherwise.
+                     -html also writes a self-contained debug_report.html (no external tools);
+                     -json exports the report model (report.json) as debug_report.json.
+                     Runs report-check first and refuses an incomplete report unless -force.
+  report-check [dir] List what the report still lacks: sections, a breakpoint hit, evidence with
+                     args/locals/stack and a successful print, ordered trace rows, evidence before
+                     the fix (dir defaults to $DBG_DIR). Exits 1 when incomplete.
 
 Templates:
   install-templates  Extract embedded LaTeX/Lua templates to ~/.local/share/delve-debug/.
 
+Sessions: every command accepts -session NAME (before or right after the command), or set
+DLV_SESSION=NAME, to use .dlv/NAME/ instead of .dlv/ — e.g. one session per process when
+debugging a client and a server together.
+Daemon: set DLV_NO_DAEMON=1 to bypass a running daemon and connect directly.
 Logging: set DLV_RPC_LOG=1 (logs to .dlv/rpc.log) or DLV_RPC_LOG=/path/to/log.
+Auto trace: with DLV_AUTO_TRACE=1 and DBG_DIR set, break/clear/continue/next/step/stepout/restart
+append their own rows to 10_trace.md; pass -reason TEXT to them or use report-annotate later.
+Transcript: set DLV_TRANSCRIPT=1 (records to .dlv/transcript.jsonl) or DLV_TRANSCRIPT=/path.
 When DBG_DIR is set (e.g. .debug_YYYY-MM-DD), .dlv is created inside it so the project root stays clean.
 `)
 }
@@ -0,0 +1,53 @@
+package delvehelper
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestExtractSession(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		name string
+		rest string
+	}{
+		{[]string{"-session", "a", "print", "x"}, "a", "print x"},
+		{[]string{"--session", "a", "print"}, "a", "print"},
+		{[]string{"-session=a", "print"}, "a", "print"},
+		{[]string{"--session=a"}, "a", ""},
+		{[]string{"session"}, "", "session"},
+		{[]string{"session=a", "x"}, "", "session=a x"},
+		{[]string{"---session", "a"}, "", "---session a"},
+		{nil, "", ""},
+	} {
+		name, rest, err := extractSession(tc.args)
+		if err != nil || name != tc.name || strings.Join(rest, " ") != tc.rest {
+			t.Errorf("extractSession(%q) = %q, %q, %v; want %q, %q", tc.args, name, rest, err, tc.name, tc.rest)
+		}
+	}
+	if _, _, err := extractSession([]string{"-session"}); err == nil {
+		t.Error("extractSession(-session) without a name: want an error")
+	}
+}
+
+// An argument named session is the command's, not the -session flag: print
+// session gets as far as connecting to Delve.
+func TestRunSessionArgument(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	t.Setenv("DLV_ADDR", addr)
+	t.Setenv("DLV_NO_DAEMON", "1")
+	t.Setenv("DLV_SESSION", "")
+	t.Setenv("DLV_TRANSCRIPT", "")
+	for _, cmd := range []string{"print", "break"} {
+		err := Run([]string{"delve-helper", cmd, "session"})
+		if err == nil || !strings.Contains(err.Error(), "connect to delve at "+addr) {
+			t.Errorf("%s session: err = %v, want a connect error", cmd, err)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+// Replay scripts: script runs delve-helper commands from a file over one RPC
+// connection, with assert-output checks, so a debugging session can be
+// committed next to the regression test it explains.
+package delvehelper
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// splitCommandLine splits a script line into words like a POSIX shell:
+// single quotes are literal, double quotes allow \" and \\, a backslash
+// outside quotes escapes the next character, and # starts a comment.
+func splitCommandLine(line string) ([]string, error) {
+	var words []string
+	var cur strings.Builder
+	inWord := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t':
+			if inWord {
+				words = append(words, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		case c == '#' && !inWord:
+			return words, nil
+		case c == '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			cur.WriteString(line[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' && i+1 < len(line) && strings.IndexByte(`"\$`+"`", line[i+1]) >= 0 {
+					i++
+				}
+				cur.WriteByte(line[i])
+			}
+			if i >= len(line) {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+			inWord = true
+		case c == '\\' && i+1 < len(line):
+			i++
+			cur.WriteByte(line[i])
+			inWord = true
+		default:
+			cur.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, cur.String())
+	}
+	return words, nil
+}
+
+type scriptStep struct {
+	Line   int      `json:"line"`
+	Cmd    string   `json:"cmd"`
+	Args   []string `json:"args,omitempty"`
+	Output string   `json:"output"`
+	Err    string   `json:"error,omitempty"`
+	OK     bool     `json:"ok"`
+}
+
+// scriptRunner executes script lines. Session commands share one connection
+// through the same type the daemon uses; everything else is dispatched
+// normally with its output captured.
+type scriptRunner struct {
+	conn       daemon
+	lastOutput string
+}
+
+// runIn runs one line with DLV_SESSION set to session for its duration, so a
+// -session on one line does not leak into the next.
+func (r *scriptRunner) runIn(session, cmd string, args []string) (string, error) {
+	if session == "" {
+		return r.run(cmd, args)
+	}
+	prev, had := os.LookupEnv("DLV_SESSION")
+	os.Setenv("DLV_SESSION", session)
+	defer func() {
+		if had {
+			os.Setenv("DLV_SESSION", prev)
+		} else {
+			os.Unsetenv("DLV_SESSION")
+		}
+	}()
+	return r.run(cmd, args)
+}
+
+func (r *scriptRunner) run(cmd string, args []string) (string, error) {
+	switch {
+	case cmd == "assert-output":
+		want := strings.Join(args, " ")
+		if want == "" {
+			return "", fmt.Errorf("usage: assert-output <text>")
+		}
+		if !strings.Contains(r.lastOutput, want) {
+			return "", fmt.Errorf("assert-output: previous output does not contain %q", want)
+		}
+		return "", nil
+	case cmd == "script" || cmd == "daemon":
+		return "", fmt.Errorf("%s cannot be used inside a script", cmd)
+	case sessionCommands[cmd] != nil:
+		resp := r.conn.exec(daemonRequest{Cmd: cmd, Args: args})
+		r.lastOutput = resp.Output
+		if resp.Err != "" {
+			return resp.Output, fmt.Errorf("%s", resp.Err)
+		}
+		return resp.Output, nil
+	}
+	// start may chdir into the target module; later lines resolve paths
+	// (and .dlv/) from where the script was started.
+	wd, _ := os.Getwd()
+	if cmd == "stop" || cmd == "start" || cmd == "connect" || cmd == "build" {
+		r.conn.close()
+	}
+	out, err := teeStdout(nil, func() error { return dispatch(cmd, args) })
+	if wd != "" {
+		_ = os.Chdir(wd)
+	}
+	r.lastOutput = out
+	return out, err
+}
+
+// cmdScript runs the commands in a script file (or - for stdin).
+func cmdScript(args []string) error {
+	fs := flag.NewFlagSet("script", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the results as one JSON document instead of text")
+	keepGoing := fs.Bool("keep-going", false, "run the remaining lines after a failure")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: script [-json] [-keep-going] <file|->")
+	}
+	var data []byte
+	var err error
+	if fs.Arg(0) == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(fs.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+
+	r := &scriptRunner{}
+	defer r.conn.close()
+	var steps []scriptStep
+	failed := 0
+	for i, line := range strings.Split(string(data), "\n") {
+		words, err := splitCommandLine(line)
+		step := scriptStep{Line: i + 1}
+		if err == nil && len(words) == 0 {
+			continue
+		}
+		if err == nil && words[0] == "delve-helper" {
+			words = words[1:]
+		}
+		var session string
+		if err == nil {
+			session, words, err = extractSession(words)
+		}
+		if err == nil && len(words) == 0 {
+			err = fmt.Errorf("missing command")
+		}
+		if err == nil {
+			step.Cmd, step.Args = strings.ToLower(words[0]), words[1:]
+			if session == "" {
+				session, step.Args, err = extractSession(step.Args)
+			}
+		}
+		if err == nil && session != "" {
+			err = validSessionName(session)
+		}
+		if err == nil {
+			if !*asJSON {
+				fmt.Printf("$ %s\n", strings.Join(append([]string{step.Cmd}, quoteArgs(step.Args)...), " "))
+			}
+			step.Output, err = r.runIn(session, step.Cmd, step.Args)
+		}
+		step.OK = err == nil
+		if err != nil {
+			step.Err = err.Error()
+			failed++
+		}
+		steps = append(steps, step)
+		if !*asJSON {
+			fmt.Print(step.Output)
+			if err != nil {
+				fmt.Printf("FAIL (line %d): %v\n", step.Line, err)
+			}
+		}
+		if err != nil && !*keepGoing {
+			break
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(struct {
+			OK    bool         `json:"ok"`
+			Steps []scriptStep `json:"steps"`
+		}{failed == 0, steps}); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("script: %d step(s), %d failed\n", len(steps), failed)
+	}
+	if failed > 0 {
+		return fmt.Errorf("script %s: %d step(s) failed", fs.Arg(0), failed)
+	}
+	return nil
+}
+
+func quoteArgs(args []string) []string {
+	out := make([]string, len(args))
+	for i, a := range args {
+		out[i] = shellQuote(a)
+	}
+	return out
+}
@@ -0,0 +1,100 @@
+// Named sessions: .dlv/ holds the default session, .dlv/<name>/ the others.
+package delvehelper
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+type sessionEntry struct {
+	name string // "default" for the session in .dlv/ itself
+	dir  string
+}
+
+// listSessions returns every session directory under the .dlv base dir that
+// has an addr file, the default session first.
+func listSessions() []sessionEntry {
+	base := getDlvBaseDir()
+	var out []sessionEntry
+	if _, err := os.Stat(filepath.Join(base, "addr")); err == nil {
+		out = append(out, sessionEntry{name: "default", dir: base})
+	}
+	entries, _ := os.ReadDir(base)
+	var named []sessionEntry
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		dir := filepath.Join(base, e.Name())
+		if _, err := os.Stat(filepath.Join(dir, "addr")); err == nil {
+			named = append(named, sessionEntry{name: e.Name(), dir: dir})
+		}
+	}
+	sort.Slice(named, func(i, j int) bool { return named[i].name < named[j].name })
+	return append(out, named...)
+}
+
+func readSessionFile(dir, name string) string {
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// sessionState connects to addr and summarizes the debugger state without
+// blocking on a running target.
+func sessionState(addr string) string {
+	client, err := newClientAt(addr)
+	if err != nil {
+		return "unreachable"
+	}
+	defer client.Disconnect(false)
+	state, err := client.GetStateNonBlocking()
+	if isExitError(err) {
+		return "exited"
+	}
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return summarizeState(state)
+}
+
+// cmdSessions prints one line per session: name, target, PID, state and port.
+func cmdSessions() error {
+	sessions := listSessions()
+	if len(sessions) == 0 {
+		fmt.Println("no active delve sessions")
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SESSION\tTARGET\tPID\tPORT\tSTATE")
+	for _, s := range sessions {
+		addr := readSessionFile(s.dir, "addr")
+		port := addr
+		if _, p, err := net.SplitHostPort(addr); err == nil {
+			port = p
+		}
+		target := readSessionFile(s.dir, "target")
+		if target == "" {
+			target = "-"
+		}
+		pid := readSessionFile(s.dir, "pid")
+		if pid == "" {
+			pid = "-"
+		}
+		var state string
+		if reason := sessionProblem(s.dir); reason != "" {
+			state = "stale: " + reason
+		} else {
+			state = sessionState(addr)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", s.name, target, pid, port, state)
+	}
+	return tw.Flush()
+}
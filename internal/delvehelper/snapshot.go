@@ -0,0 +1,103 @@
+// snapshot: capture state, args, locals, stack, expressions and source
+// context at the current stop in one session, optionally as report evidence.
+package delvehelper
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/go-delve/delve/service/api"
+)
+
+// capture runs fn with the package stdout redirected and returns what it
+// printed; an error is rendered in the text so one failing part does not
+// lose the rest of the snapshot.
+func capture(fn func() error) string {
+	old := stdout
+	var buf bytes.Buffer
+	stdout = &buf
+	err := fn()
+	stdout = old
+	if err != nil {
+		fmt.Fprintf(&buf, "<error: %v>\n", err)
+	}
+	return buf.String()
+}
+
+// cmdSnapshot prints everything report-evidence needs at the current stop
+// and, with -dbg, appends it as an evidence block.
+func cmdSnapshot(client *loggingClient, state *api.DebuggerState, args []string) error {
+	fs := flag.NewFlagSet("snapshot", flag.ContinueOnError)
+	dbg := fs.String("dbg", "", "artifact dir: append the snapshot to 20_evidence.md")
+	var prints stringsFlag
+	fs.Var(&prints, "print", "expression to evaluate (repeatable)")
+	loc := fs.String("loc", "", "evidence heading (default: file:line of the stop)")
+	ctx := fs.Int("ctx", 2, "lines of source context above and below the stop line")
+	outputLines := fs.Int("output", 0, "attach the last N lines of target output (requires start -redirect)")
+	obs := fs.String("obs", "", "one-sentence observation for the evidence block")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if state.Running {
+		return fmt.Errorf("process is running; stop it first (halt or wait)")
+	}
+	if state.Exited || state.SelectedGoroutine == nil {
+		return fmt.Errorf("no stopped goroutine to snapshot")
+	}
+
+	ev := evidence{Loc: *loc, Ctx: *ctx, OutputLines: *outputLines, Obs: *obs}
+	cur := state.SelectedGoroutine.UserCurrentLoc
+	if cur.File == "" {
+		cur = state.SelectedGoroutine.CurrentLoc
+	}
+	if ev.Loc == "" {
+		ev.Loc, _ = stopLocation(state)
+	}
+	ev.SrcFile, ev.Highlight = localPath(cur.File), cur.Line
+
+	stateOut := capture(func() error { return printState(state) })
+	ev.Args = capture(func() error { return cmdArgs(client, state) })
+	ev.Locals = capture(func() error { return cmdLocals(client, state) })
+	ev.Stack = capture(func() error { return cmdStack(client, state) })
+	for _, expr := range prints {
+		val := capture(func() error { return cmdPrint(client, state, []string{expr}) })
+		ev.Prints = append(ev.Prints, evidencePrint{Expr: expr, Val: val})
+	}
+
+	section := func(title, text string) {
+		fmt.Fprintf(stdout, "== %s ==\n%s", title, text)
+		if text == "" || !strings.HasSuffix(text, "\n") {
+			fmt.Fprintln(stdout)
+		}
+	}
+	section("state", stateOut)
+	if lines, first, err := readSourceContext(ev.SrcFile, ev.Highlight, ev.Ctx); err == nil {
+		var sb strings.Builder
+		for i, l := range lines {
+			mark := "  "
+			if first+i == ev.Highlight {
+				mark = "=>"
+			}
+			fmt.Fprintf(&sb, "%s %4d  %s\n", mark, first+i, l)
+		}
+		section("source "+ev.Loc, sb.String())
+	}
+	section("args", ev.Args)
+	section("locals", ev.Locals)
+	section("stack", ev.Stack)
+	for _, p := range ev.Prints {
+		section("print "+p.Expr, p.Val)
+	}
+
+	if *dbg == "" {
+		return nil
+	}
+	ref, err := writeEvidence(*dbg, ev)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "appended evidence %sfor %s to %s\n", ref, ev.Loc, rfile(*dbg, reportEvidFile))
+	return nil
+}
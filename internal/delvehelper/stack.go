@@ -0,0 +1,113 @@
+// Go traceback parsing: panic messages, goroutine headers and stack frames
+// as printed by the runtime (and go test) when a program panics.
+package delvehelper
+
+import (
+	"bufio"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type stackFrame struct {
+	Func   string // e.g. main.(*Pipeline).Run
+	File   string
+	Line   int
+	Offset string // PC offset within the function, e.g. +0x1d; "" if not printed
+}
+
+type goroutineTrace struct {
+	ID        int
+	State     string // e.g. running, chan receive
+	Frames    []stackFrame
+	CreatedBy *stackFrame
+}
+
+type traceback struct {
+	Panic      string // panic message(s), "" for a plain goroutine dump
+	Goroutines []goroutineTrace
+}
+
+var (
+	goroutineHeaderRE = regexp.MustCompile(`^goroutine (\d+)(?: [^\[]*)?\[([^\]]+)\]:$`)
+	fileLineRE        = regexp.MustCompile(`^\s+(.+\.(?:go|s)):(\d+)(?: (\+0x[0-9a-f]+))?`)
+)
+
+// parseTraceback extracts the panic message and goroutine stacks from text.
+// Lines that are not part of a traceback (test output, logs) are ignored.
+func parseTraceback(text string) traceback {
+	var tb traceback
+	var cur *goroutineTrace
+	var pending *stackFrame // function line waiting for its file:line
+	createdBy := false
+
+	flush := func() {
+		if cur != nil {
+			tb.Goroutines = append(tb.Goroutines, *cur)
+		}
+		cur, pending = nil, nil
+	}
+
+	sc := bufio.NewScanner(strings.NewReader(text))
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "panic: ") || strings.HasPrefix(trimmed, "fatal error: "):
+			if cur == nil {
+				if tb.Panic != "" {
+					tb.Panic += "\n"
+				}
+				tb.Panic += trimmed
+			}
+		case goroutineHeaderRE.MatchString(trimmed):
+			flush()
+			m := goroutineHeaderRE.FindStringSubmatch(trimmed)
+			id, _ := strconv.Atoi(m[1])
+			cur = &goroutineTrace{ID: id, State: m[2]}
+		case cur == nil:
+		case trimmed == "":
+			flush()
+		case fileLineRE.MatchString(line) && pending != nil:
+			m := fileLineRE.FindStringSubmatch(line)
+			pending.File = m[1]
+			pending.Line, _ = strconv.Atoi(m[2])
+			pending.Offset = m[3]
+			if createdBy {
+				cur.CreatedBy = pending
+			} else {
+				cur.Frames = append(cur.Frames, *pending)
+			}
+			pending, createdBy = nil, false
+		case strings.HasPrefix(trimmed, "created by "):
+			fn := strings.TrimPrefix(trimmed, "created by ")
+			fn, _, _ = strings.Cut(fn, " in goroutine ")
+			pending, createdBy = &stackFrame{Func: fn}, true
+		case strings.HasPrefix(trimmed, "..."):
+			// "...additional frames elided..."
+		default:
+			fn := trimmed
+			if i := strings.LastIndex(fn, "("); i > 0 && strings.HasSuffix(fn, ")") {
+				fn = fn[:i]
+			}
+			pending, createdBy = &stackFrame{Func: fn}, false
+		}
+	}
+	flush()
+	return tb
+}
+
+// isRuntimeFrame reports whether f belongs to the runtime or the testing
+// harness rather than to user code.
+func isRuntimeFrame(f stackFrame) bool {
+	if f.Func == "panic" || strings.HasSuffix(f.File, "_testmain.go") {
+		return true
+	}
+	for _, pkg := range []string{"runtime", "testing", "reflect"} {
+		if strings.HasPrefix(f.Func, pkg+".") || strings.Contains(f.File, "/src/"+pkg+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package delvehelper
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const sampleTraceback = `processing 3 readings
+panic: runtime error: invalid memory address or nil pointer dereference [recovered]
+	panic: runtime error: invalid memory address or nil pointer dereference
+[signal SIGSEGV: segmentation violation code=0x1 addr=0x8 pc=0x4820ce]
+
+goroutine 7 gp=0xc000007c00 m=0 mp=0x5a0e40 [running]:
+example.com/app/pipeline.validate(...)
+	/app/pipeline/pipeline.go:11
+example.com/app/pipeline.(*Stage).Run(0xc0000a4000, {0xc0000b6000, 0x3, 0x4})
+	/app/pipeline/pipeline.go:18 +0x6e
+created by main.main in goroutine 1
+	/app/main.go:15 +0x85
+
+goroutine 1 [chan receive, 2 minutes]:
+main.main()
+	/app/main.go:16 +0x9a
+...additional frames elided...
+`
+
+func TestParseTraceback(t *testing.T) {
+	tb := parseTraceback(sampleTraceback)
+	wantPanic := "panic: runtime error: invalid memory address or nil pointer dereference [recovered]\n" +
+		"panic: runtime error: invalid memory address or nil pointer dereference"
+	if tb.Panic != wantPanic {
+		t.Errorf("Panic = %q, want %q", tb.Panic, wantPanic)
+	}
+	if len(tb.Goroutines) != 2 {
+		t.Fatalf("got %d goroutines, want 2", len(tb.Goroutines))
+	}
+	g := tb.Goroutines[0]
+	if g.ID != 7 || g.State != "running" {
+		t.Errorf("goroutine = %d [%s], want 7 [running]", g.ID, g.State)
+	}
+	wantFrames := []stackFrame{
+		{Func: "example.com/app/pipeline.validate", File: "/app/pipeline/pipeline.go", Line: 11},
+		{Func: "example.com/app/pipeline.(*Stage).Run", File: "/app/pipeline/pipeline.go", Line: 18, Offset: "+0x6e"},
+	}
+	if len(g.Frames) != len(wantFrames) {
+		t.Fatalf("got %d frames, want %d: %+v", len(g.Frames), len(wantFrames), g.Frames)
+	}
+	for i, f := range g.Frames {
+		if f != wantFrames[i] {
+			t.Errorf("frame %d = %+v, want %+v", i, f, wantFrames[i])
+		}
+	}
+	if g.CreatedBy == nil || g.CreatedBy.Func != "main.main" || g.CreatedBy.Line != 15 {
+		t.Errorf("CreatedBy = %+v, want main.main at main.go:15", g.CreatedBy)
+	}
+	if g1 := tb.Goroutines[1]; g1.State != "chan receive, 2 minutes" || len(g1.Frames) != 1 {
+		t.Errorf("goroutine 1 = %+v", g1)
+	}
+}
+
+func TestFuncPackage(t *testing.T) {
+	for fn, want := range map[string]string{
+		"main.main.func1":                       "main",
+		"example.com/app/pipeline.(*Stage).Run": "example.com/app/pipeline",
+		"runtime.gopanic":                       "runtime",
+	} {
+		if got := funcPackage(fn); got != want {
+			t.Errorf("funcPackage(%q) = %q, want %q", fn, got, want)
+		}
+	}
+}
+
+func TestPanicSuggestionsMapsRemotePaths(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "pipeline"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"main.go", "pipeline/pipeline.go"} {
+		if err := os.WriteFile(filepath.Join(root, f), []byte("package x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := panicSuggestions(parseTraceback(sampleTraceback), root, "example.com/app")
+	want := []string{"pipeline.go:11", "pipeline.go:18", "main.go:15"}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v, want locations %v", got, want)
+	}
+	for i, s := range got {
+		if s.Loc != want[i] {
+			t.Errorf("suggestion %d = %s, want %s", i, s.Loc, want[i])
+		}
+	}
+}
@@ -3,12 +3,14 @@ package delvehelper
 
 import (
 	"bufio"
+	"bytes"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"syscall"
@@ -42,21 +44,114 @@ func startDetached(cmd *exec.Cmd) error {
 	return cmd.Start()
 }
 
+// leadingFlags returns how many of args, from the start, are flags defined
+// in fs together with their values. It stops at "--" and at the first
+// argument fs does not define.
+func leadingFlags(fs *flag.FlagSet, args []string) int {
+	i := 0
+	for i < len(args) {
+		a := args[i]
+		if a == "--" || len(a) < 2 || a[0] != '-' {
+			break
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(a, "-"), "=")
+		f := fs.Lookup(name)
+		if f == nil {
+			break
+		}
+		i++
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !hasValue && !(ok && b.IsBoolFlag()) {
+			i++ // the flag's value
+		}
+	}
+	return min(i, len(args))
+}
+
 func cmdStart(args []string) error {
 	fs := flag.NewFlagSet("start", flag.ContinueOnError)
 	testMode := fs.Bool("test", false, "run dlv test instead of dlv debug")
 	execMode := fs.Bool("exec", false, "run dlv exec instead of dlv debug")
+	redirect := fs.Bool("redirect", false, "send target stdout/stderr to .dlv/target.out and .dlv/target.err")
+	stdinFile := fs.String("stdin", "", "feed this file to the target's stdin via .dlv/target.in (implies -redirect)")
+	var rules substituteFlag
+	fs.Var(&rules, "substitute-path", "map a build-time source dir to a local dir: from=to (repeatable)")
+	listen := fs.String("listen", "127.0.0.1:0", "address for the Delve API server (host:port)")
+	startupTimeout := fs.Duration("startup-timeout", 15*time.Second, "how long to wait for dlv to build and start listening")
+	buildFlags := fs.String("build-flags", "", "flags passed to the compiler, e.g. \"-tags=integration -race\" (not with -exec)")
+	wd := fs.String("wd", "", "working directory for the target program")
+	backend := fs.String("backend", "", "Delve backend: default | native | lldb | rr")
+	var env stringsFlag
+	fs.Var(&env, "env", "KEY=VAL added to the target's environment (repeatable)")
+	runTest := fs.String("run", "", "with -test: debug only this test (TestA or TestA/subtest), anchored, with a breakpoint at its entry")
+	failing := fs.Bool("failing", false, "with -test: run go test -json first and debug the failing test")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
 	rest := fs.Args()
+	// With -test, start flags may follow the package too: start -test ./pkg
+	// -run TestX. Anything from the first other argument on is the test's.
+	// Program arguments in the other modes are never read as start flags.
+	if *testMode && len(rest) > 1 {
+		n := leadingFlags(fs, rest[1:])
+		if err := fs.Parse(rest[1 : 1+n]); err != nil {
+			return err
+		}
+		rest = append(rest[:1:1], rest[1+n:]...)
+	}
+	if len(rest) > 1 && rest[1] == "--" {
+		rest = append(rest[:1:1], rest[2:]...)
+	}
+	for _, kv := range env {
+		if k, _, ok := strings.Cut(kv, "="); !ok || k == "" {
+			return fmt.Errorf("invalid -env %q (want KEY=VAL)", kv)
+		}
+	}
+	if *buildFlags != "" && *execMode {
+		return fmt.Errorf("-build-flags has no effect with -exec (the binary is already built)")
+	}
 	if *testMode && *execMode {
 		return fmt.Errorf("cannot use -test and -exec together")
 	}
+	if (*runTest != "" || *failing) && !*testMode {
+		return fmt.Errorf("-run and -failing require -test")
+	}
+	if *runTest != "" && *failing {
+		return fmt.Errorf("cannot use -run and -failing together")
+	}
 	target := "."
 	if len(rest) > 0 {
 		target = rest[0]
 	}
+	mode := "debug"
+	switch {
+	case *testMode:
+		mode = "test"
+	case *execMode:
+		mode = "exec"
+	}
+	targetDesc := mode + " " + target
+	if *failing {
+		dir, pkg := "", target
+		if _, err := os.Stat(filepath.Join(target, "go.mod")); err == nil && target != "." {
+			dir, pkg = target, "."
+		}
+		name, err := pickFailingTest(dir, pkg, target)
+		if err != nil {
+			return err
+		}
+		*runTest = name
+	}
+	var testArgs []string
+	if len(rest) > 1 {
+		testArgs = rest[1:]
+	}
+	if *runTest != "" {
+		testArgs = append([]string{"-test.run", testRunPattern(*runTest)}, testArgs...)
+		targetDesc += " -run " + *runTest
+	}
+	if *execMode {
+		warnBinary(target)
+	}
 
 	// Fix #5: if target is a directory with its own go.mod (separate module),
 	// chdir into it and use "." so dlv debug runs in the right module context.
@@ -64,6 +159,9 @@ func cmdStart(args []string) error {
 	// subsequent delve-helper commands from the caller's directory to find the session.
 	origCWD, _ := os.Getwd()
 	didChdir := false
+	if *wd != "" && !filepath.IsAbs(*wd) {
+		*wd = filepath.Join(origCWD, *wd)
+	}
 	if target != "." && !*execMode {
 		if _, err := os.Stat(filepath.Join(target, "go.mod")); err == nil {
 			if err := os.Chdir(target); err != nil {
@@ -74,12 +172,38 @@ func cmdStart(args []string) error {
 		}
 	}
 
+	// .dlv/addr and .dlv/pid go under DBG_DIR/.dlv if DBG_DIR is set (so .dlv lives in the debug artifact dir).
+	// When we chdired into a submodule, resolve the path from origCWD so the file is under project root.
+	dlvDir := getDlvDir()
+	if didChdir {
+		dlvDir = filepath.Join(origCWD, dlvDir)
+	}
+	if err := os.MkdirAll(dlvDir, 0755); err != nil {
+		return err
+	}
+
 	dlvPath, err := findDlv()
 	if err != nil {
 		return err
 	}
 	debugBin := filepath.Join(os.TempDir(), "dlv-"+strconv.FormatInt(time.Now().UnixNano(), 10))
-	dlvArgs := []string{"--headless", "--accept-multiclient", "--api-version=2"}
+	dlvArgs := []string{"--headless", "--accept-multiclient", "--api-version=2", "--listen=" + *listen}
+	if *buildFlags != "" {
+		dlvArgs = append(dlvArgs, "--build-flags="+*buildFlags)
+	}
+	if *wd != "" {
+		dlvArgs = append(dlvArgs, "--wd="+*wd)
+	}
+	if *backend != "" {
+		dlvArgs = append(dlvArgs, "--backend="+*backend)
+	}
+	if *redirect || *stdinFile != "" {
+		redirects, err := prepareRedirects(dlvDir, *stdinFile)
+		if err != nil {
+			return err
+		}
+		dlvArgs = append(dlvArgs, redirects...)
+	}
 	switch {
 	case *execMode:
 		dlvArgs = append(dlvArgs, "exec", target)
@@ -89,12 +213,14 @@ func cmdStart(args []string) error {
 		}
 	case *testMode:
 		dlvArgs = append(dlvArgs, "test", "--output", debugBin, target)
-		if len(rest) > 1 {
-			dlvArgs = append(dlvArgs, rest[1:]...)
+		if len(testArgs) > 0 {
+			dlvArgs = append(dlvArgs, "--")
+			dlvArgs = append(dlvArgs, testArgs...)
 		}
 	default:
 		dlvArgs = append(dlvArgs, "debug", "--output", debugBin, target)
 		if len(rest) > 1 {
+			dlvArgs = append(dlvArgs, "--")
 			dlvArgs = append(dlvArgs, rest[1:]...)
 		}
 	}
@@ -115,17 +241,34 @@ func cmdStart(args []string) error {
 	}
 	tmpPath := tmpOut.Name()
 
+	// dlv's stderr (compiler output, then dlv's own diagnostics and the
+	// target's stderr unless redirected) goes to a file for the same reason.
+	logPath := filepath.Join(dlvDir, "dlv.log")
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		tmpOut.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("create dlv log: %w", err)
+	}
+
 	cmd := exec.Command(dlvPath, dlvArgs...)
-	cmd.Stderr = os.Stderr
+	cmd.Stderr = logFile
 	cmd.Stdout = tmpOut
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
 
 	if err := startDetached(cmd); err != nil {
 		tmpOut.Close()
+		logFile.Close()
 		os.Remove(tmpPath)
 		return err
 	}
 	tmpOut.Close()           // close our write-side copy; dlv's inherited fd stays open
 	defer os.Remove(tmpPath) // unlink after we've read the address
+	logFile.Close()
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
 
 	// Poll the temp file until dlv writes "API server listening at: <addr>".
 	tmpIn, err := os.Open(tmpPath)
@@ -136,8 +279,13 @@ func cmdStart(args []string) error {
 
 	const prefix = "API server listening at: "
 	var addr string
-	deadline := time.Now().Add(15 * time.Second)
+	deadline := time.Now().Add(*startupTimeout)
 	for time.Now().Before(deadline) {
+		select {
+		case err := <-exited:
+			return startFailure(fmt.Sprintf("dlv exited before listening (%v)", err), tmpPath, logPath)
+		default:
+		}
 		if _, err := tmpIn.Seek(0, io.SeekStart); err != nil {
 			return fmt.Errorf("seek dlv output: %w", err)
 		}
@@ -149,69 +297,142 @@ func cmdStart(args []string) error {
 				break
 			}
 			if line != "" {
-				return fmt.Errorf("unexpected dlv output: %s", line)
+				_ = cmd.Process.Kill()
+				return startFailure("unexpected dlv output: "+line, tmpPath, logPath)
 			}
 		}
 		time.Sleep(50 * time.Millisecond)
 	}
 	if addr == "" {
-		return fmt.Errorf("timed out waiting for dlv to start")
+		_ = cmd.Process.Kill()
+		return startFailure(fmt.Sprintf("timed out after %s waiting for dlv to start (raise -startup-timeout for slow builds)", *startupTimeout), tmpPath, logPath)
 	}
 
-	// Write .dlv/addr and .dlv/pid. If DBG_DIR is set, use DBG_DIR/.dlv (so .dlv lives in the debug artifact dir).
-	// When we chdired into a submodule, resolve the path from origCWD so the file is under project root.
-	dlvDir := getDlvDir()
-	if didChdir {
-		dlvDir = filepath.Join(origCWD, dlvDir)
-	}
-	if err := os.MkdirAll(dlvDir, 0755); err != nil {
-		return err
-	}
 	addrFile := filepath.Join(dlvDir, "addr")
 	pidFile := filepath.Join(dlvDir, "pid")
 	if err := os.WriteFile(addrFile, []byte(addr+"\n"), 0644); err != nil {
 		return err
 	}
 	_ = os.WriteFile(pidFile, []byte(strconv.Itoa(cmd.Process.Pid)+"\n"), 0644)
+	_ = os.WriteFile(filepath.Join(dlvDir, "target"), []byte(targetDesc+"\n"), 0644)
+	if err := saveSubstituteRules(dlvDir, rules); err != nil {
+		return err
+	}
+	recordStart(dlvDir, targetDesc)
 	// If we auto-chdired and DBG_DIR is not set, also write to the caller's cwd so subsequent commands find the session.
 	if didChdir && os.Getenv("DBG_DIR") == "" {
-		callerDlv := filepath.Join(origCWD, ".dlv")
+		callerDlv := filepath.Join(origCWD, getDlvDir())
 		if err := os.MkdirAll(callerDlv, 0755); err == nil {
 			os.WriteFile(filepath.Join(callerDlv, "addr"), []byte(addr+"\n"), 0644)
 			os.WriteFile(filepath.Join(callerDlv, "pid"), []byte(strconv.Itoa(cmd.Process.Pid)+"\n"), 0644)
 		}
 	}
 	fmt.Println("headless dlv started, address written to", addrFile)
+	fmt.Println("dlv log:", logPath)
+	if *redirect || *stdinFile != "" {
+		fmt.Println("target output redirected to", filepath.Join(dlvDir, targetOutFile), "and", filepath.Join(dlvDir, targetErrFile))
+	}
 	fmt.Println(addr)
+	if *runTest != "" {
+		if err := breakAtTest(addr, *runTest); err != nil {
+			fmt.Printf("warning: could not set a breakpoint at %s: %v\n", topLevelTest(*runTest), err)
+		}
+	}
 	return nil
 }
 
-// cmdStop terminates a running Delve session started by cmdStart.
-// Reads the PID from .dlv/pid (or DBG_DIR/.dlv/pid), sends SIGTERM, and removes the .dlv files.
-func cmdStop() error {
-	dlvDir := getDlvDir()
-	pidFile := filepath.Join(dlvDir, "pid")
-	data, err := os.ReadFile(pidFile)
-	if err != nil {
-		fmt.Println("no active delve session (pid file not found)")
+// stringsFlag collects a repeatable string flag.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string     { return strings.Join(*f, ",") }
+func (f *stringsFlag) Set(v string) error { *f = append(*f, v); return nil }
+
+// compilerErrorRE matches an error line of the Go compiler or vet, as dlv
+// debug/test pass them through: file.go:LINE:COL: message.
+var compilerErrorRE = regexp.MustCompile(`(?m)^\S+\.go:\d+:\d+: `)
+
+// startFailure builds the error for a dlv that never started listening. dlv
+// passes the compiler output through, so a build failure (a compiler error
+// line, not just any output) is reported as such with the full output
+// instead of only its first line.
+func startFailure(reason, stdoutPath, logPath string) error {
+	var out strings.Builder
+	for _, p := range []string{stdoutPath, logPath} {
+		if b, err := os.ReadFile(p); err == nil && len(bytes.TrimSpace(b)) > 0 {
+			out.Write(bytes.TrimRight(b, "\n"))
+			out.WriteString("\n")
+		}
+	}
+	text := strings.TrimRight(out.String(), "\n")
+	if text == "" {
+		return fmt.Errorf("%s (no output; see %s)", reason, logPath)
+	}
+	if compilerErrorRE.MatchString(text) {
+		return fmt.Errorf("build failed; compiler output (also in %s):\n%s", logPath, text)
+	}
+	return fmt.Errorf("%s; dlv output (also in %s):\n%s", reason, logPath, text)
+}
+
+// cmdStop terminates a running Delve session started by cmdStart, or every
+// session (default and named) with -all.
+func cmdStop(args []string) error {
+	fs := flag.NewFlagSet("stop", flag.ContinueOnError)
+	all := fs.Bool("all", false, "stop every session under .dlv/")
+	timeout := fs.Duration("timeout", 3*time.Second, "how long to wait after each stop attempt before escalating")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !*all {
+		return stopSession(getDlvDir(), *timeout)
+	}
+	sessions := listSessions()
+	if len(sessions) == 0 {
+		fmt.Println("no active delve sessions")
 		return nil
 	}
-	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
-	if err != nil {
-		return fmt.Errorf("invalid pid in %s: %w", pidFile, err)
+	for _, s := range sessions {
+		fmt.Printf("[%s]\n", s.name)
+		if err := stopSession(s.dir, *timeout); err != nil {
+			return err
+		}
 	}
-	proc, err := os.FindProcess(pid)
-	if err != nil {
-		fmt.Printf("process %d not found; cleaning up\n", pid)
+	return nil
+}
+
+// stopSession stops the daemon if one is running, then shuts Delve down,
+// escalating from a Detach(kill) RPC to SIGTERM to SIGKILL, and removes the
+// session files. A PID that no longer belongs to dlv is never signalled.
+func stopSession(dlvDir string, timeout time.Duration) error {
+	stopDaemon(filepath.Join(dlvDir, "daemon.sock"))
+	pid, hasPid := readSessionPid(dlvDir)
+	addr := readSessionFile(dlvDir, "addr")
+	if !hasPid && addr == "" {
+		fmt.Println("no active delve session (pid file not found)")
+		return nil
+	}
+	if !hasPid {
+		// Recorded by connect: the server is not ours to kill.
+		fmt.Printf("forgetting remote session at %s (server left running)\n", addr)
+	} else if reason := sessionProblem(dlvDir); reason != "" {
+		fmt.Printf("%s; not signalling it\n", reason)
 	} else {
-		if err := proc.Signal(syscall.SIGTERM); err != nil {
-			fmt.Printf("signal: %v (process may have already exited)\n", err)
-		} else {
-			fmt.Printf("sent SIGTERM to delve (pid %d)\n", pid)
+		if addr != "" {
+			if client, err := newClientAt(addr); err == nil {
+				if err := client.Detach(true); err == nil {
+					fmt.Println("detached from delve and killed the target")
+				}
+			}
+		}
+		if !waitExit(pid, timeout) {
+			terminateDlv(pid, timeout)
 		}
 	}
-	os.Remove(filepath.Join(dlvDir, "addr"))
-	os.Remove(pidFile)
+	for _, name := range []string{"addr", "pid", "target", substitutePathFile} {
+		os.Remove(filepath.Join(dlvDir, name))
+	}
+	if filepath.Clean(dlvDir) != filepath.Clean(getDlvBaseDir()) {
+		os.Remove(dlvDir) // named session dir; kept if it still holds logs
+	}
 	fmt.Println("session cleaned up")
 	return nil
 }
@@ -0,0 +1,127 @@
+package delvehelper
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// fakeDlv records its arguments, announces a listen address like dlv
+// --headless and stays alive until killed.
+const fakeDlv = `#!/bin/sh
+printf '%s\n' "$@" > "$FAKE_DLV_ARGS"
+echo "API server listening at: 127.0.0.1:1"
+exec sleep 30
+`
+
+// installFakeDlv puts script on PATH as dlv, points DBG_DIR at a temp dir
+// and returns the file the script may record its arguments in.
+func installFakeDlv(t *testing.T, script string) string {
+	t.Helper()
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "dlv"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	argsFile := filepath.Join(bin, "args")
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("FAKE_DLV_ARGS", argsFile)
+	t.Setenv("DBG_DIR", t.TempDir())
+	t.Setenv("DLV_SESSION", "")
+	return argsFile
+}
+
+// startArgs runs start with a fake dlv on PATH and returns the arguments
+// after --listen that dlv received.
+func startArgs(t *testing.T, args ...string) []string {
+	t.Helper()
+	argsFile := installFakeDlv(t, fakeDlv)
+	err := cmdStart(args)
+	if pid, ok := readSessionPid(getDlvDir()); ok {
+		_ = syscall.Kill(pid, syscall.SIGKILL)
+	}
+	if err != nil {
+		t.Fatalf("start %q: %v", args, err)
+	}
+	b, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	for i, a := range got {
+		if strings.HasPrefix(a, "--listen=") {
+			return got[i+1:]
+		}
+	}
+	t.Fatalf("no --listen in dlv args %q", got)
+	return nil
+}
+
+func TestStartProgramArgs(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		want string // dlv arguments after --listen; <bin> is the -test output
+	}{
+		{[]string{"-exec", "./app", "-port", "8080"}, "exec ./app -- -port 8080"},
+		{[]string{"-exec", "./app", "-env", "X=1", "-wd", "/"}, "exec ./app -- -env X=1 -wd /"},
+		{[]string{"-exec", "./app", "--", "-listen", ":0"}, "exec ./app -- -listen :0"},
+		{[]string{"./cmd/app", "-backend", "rr"}, "debug --output <bin> ./cmd/app -- -backend rr"},
+		{[]string{"-test", "./pkg", "-backend", "native", "-wd=/tmp", "-test.v", "-env", "X=1"},
+			"--wd=/tmp --backend=native test --output <bin> ./pkg -- -test.v -env X=1"},
+		{[]string{"-test", "./pkg", "--", "-backend", "native"}, "test --output <bin> ./pkg -- -backend native"},
+	} {
+		got := startArgs(t, tc.args...)
+		for i := range got {
+			if i > 0 && got[i-1] == "--output" {
+				got[i] = "<bin>"
+			}
+		}
+		if s := strings.Join(got, " "); s != tc.want {
+			t.Errorf("start %q: dlv %s, want %s", tc.args, s, tc.want)
+		}
+	}
+}
+
+func TestLeadingFlags(t *testing.T) {
+	fs := flag.NewFlagSet("start", flag.ContinueOnError)
+	fs.Bool("redirect", false, "")
+	fs.String("run", "", "")
+	for _, tc := range []struct {
+		args []string
+		want int
+	}{
+		{nil, 0},
+		{[]string{"-v"}, 0},
+		{[]string{"-redirect", "-run", "TestX", "-count", "1"}, 3},
+		{[]string{"--run=TestX", "--", "-redirect"}, 1},
+		{[]string{"-run"}, 1},
+	} {
+		if got := leadingFlags(fs, tc.args); got != tc.want {
+			t.Errorf("leadingFlags(%q) = %d, want %d", tc.args, got, tc.want)
+		}
+	}
+}
+
+// Only a compiler error line makes a failed start a build failure; program output that merely looks like go build's does not.
+func TestStartFailureBuildDetection(t *testing.T) {
+	for _, tc := range []struct {
+		script string
+		build  bool
+	}{
+		{"#!/bin/sh\necho '# Results'\necho 'child: exit status 1'\n" +
+			"echo 'could not launch process: build failed elsewhere' >&2\nexit 1\n", false},
+		// As dlv does it: compiler output on stdout, the go build error on stderr.
+		{"#!/bin/sh\necho '# example'\necho './pipeline.go:27:2: undefined: x'\necho 'exit status 1' >&2\nexit 1\n", true},
+	} {
+		installFakeDlv(t, tc.script)
+		err := cmdStart([]string{"."})
+		if err == nil {
+			t.Fatal("start succeeded with a dlv that exits")
+		}
+		if got := strings.HasPrefix(err.Error(), "build failed"); got != tc.build {
+			t.Errorf("build failure = %v, want %v:\n%v", got, tc.build, err)
+		}
+	}
+}
@@ -1,4 +1,4 @@
-// Embedded LaTeX/Markdown templates and install command.
+// Embedded LaTeX/Markdown/HTML templates and install command.
 package delvehelper
 
 import (
@@ -9,7 +9,7 @@ import (
 	"path/filepath"
 )
 
-//go:embed templates/tex/* templates/md/* templates/lua/*
+//go:embed templates/tex/* templates/md/* templates/lua/* templates/html/*
 var templateFS embed.FS
 
 func cmdInstallTemplates() error {
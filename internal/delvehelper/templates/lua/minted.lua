@@ -2,6 +2,7 @@
 minted -- enable the minted environment for code listings in beamer and latex.
 Adapted from https://github.com/pandoc/lua-filters/tree/master/minted
 Supports attributes: highlightlines, firstnumber, highlightcolor (for breakpoint highlighting).
+Also renders [status]{.badge .confirmed} spans as \statusbadge from styles.tex.
 ]]
 local minted_default_block_language = "text"
 local minted_block_attributes = {"autogobble"}
@@ -33,19 +34,63 @@ local function minted_attributes(block)
   return table.concat(opts, ",")
 end
 
+-- A line starting with \end{minted} would end the environment early: typeset
+-- its backslash through escapeinside with a character the code does not use.
+local function minted_guard(text, attributes)
+  local esc
+  for c in ("@!|^~`"):gmatch(".") do
+    if not text:find(c, 1, true) then
+      esc = c
+      break
+    end
+  end
+  local lines = {}
+  for line in (text .. "\n"):gmatch("(.-)\n") do
+    local indent, rest = line:match("^(%s*)(\\end{minted}.*)$")
+    if rest and esc then
+      line = indent .. esc .. "\\textbackslash{}" .. esc .. rest:sub(2)
+      if not attributes:find("escapeinside", 1, true) then
+        attributes = attributes .. ",escapeinside=" .. esc .. esc
+      end
+    elseif rest then
+      line = indent .. "\\ " .. rest:sub(2)
+    end
+    table.insert(lines, line)
+  end
+  return table.concat(lines, "\n"), attributes
+end
+
 function CodeBlock(block)
   if FORMAT == "beamer" or FORMAT == "latex" then
     local language = minted_language(block)
-    local attributes = minted_attributes(block)
+    local text, attributes = minted_guard(block.text, minted_attributes(block))
     local raw = string.format(
       "\\begin{minted}[%s]{%s}\n%s\n\\end{minted}",
       attributes,
       language,
-      block.text
+      text
     )
     return pandoc.RawBlock("latex", raw)
   end
   return block
 end
 
-return {{CodeBlock = CodeBlock}}
+local badge_palettes = {confirmed = "ok", refuted = "bad"}
+
+function Span(span)
+  if (FORMAT == "beamer" or FORMAT == "latex") and span.classes:includes("badge") then
+    local palette = "open"
+    for _, c in ipairs(span.classes) do
+      palette = badge_palettes[c] or palette
+    end
+    local out = {pandoc.RawInline("latex", "\\statusbadge{" .. palette .. "}{")}
+    for _, inline in ipairs(span.content) do
+      table.insert(out, inline)
+    end
+    table.insert(out, pandoc.RawInline("latex", "}"))
+    return out
+  end
+  return span
+end
+
+return {{CodeBlock = CodeBlock, Span = Span}}
@@ -0,0 +1,158 @@
+// Single-test focus: anchored -test.run patterns for start -test -run, and
+// discovery of failing tests through go test -json for start -test -failing.
+package delvehelper
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// testEvent is one line of go test -json (test2json) output.
+type testEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Output  string
+	Elapsed float64
+}
+
+// testRunPattern anchors each level of a test name so that -run TestA/case_3
+// matches exactly that test and subtest, not TestAB or case_30.
+func testRunPattern(name string) string {
+	parts := strings.Split(name, "/")
+	for i, p := range parts {
+		parts[i] = "^" + regexp.QuoteMeta(p) + "$"
+	}
+	return strings.Join(parts, "/")
+}
+
+// topLevelTest returns the test function of a possibly nested test name.
+func topLevelTest(name string) string {
+	top, _, _ := strings.Cut(name, "/")
+	return top
+}
+
+// runTestJSON runs go test -json -count=1 on pkg from dir and returns its
+// events. A failing test run is not an error; a go command that produced no
+// events (bad flags, missing package) is.
+func runTestJSON(dir, pkg string, extra ...string) ([]testEvent, error) {
+	args := append([]string{"test", "-json", "-count=1"}, extra...)
+	args = append(args, pkg)
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, runErr := cmd.Output()
+
+	var events []testEvent
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		var ev testEvent
+		if err := json.Unmarshal(sc.Bytes(), &ev); err == nil && ev.Action != "" {
+			events = append(events, ev)
+		}
+	}
+	if len(events) == 0 && runErr != nil {
+		return nil, fmt.Errorf("go %s: %w\n%s", strings.Join(args, " "), runErr, strings.TrimSpace(stderr.String()))
+	}
+	return events, nil
+}
+
+// failingTests returns the failing tests in events, leaves only: when
+// TestA/case_3 fails, its parent TestA (which fails because of it) is dropped.
+func failingTests(events []testEvent) []string {
+	failed := map[string]bool{}
+	for _, ev := range events {
+		if ev.Action == "fail" && ev.Test != "" {
+			failed[ev.Test] = true
+		}
+	}
+	var leaves []string
+	for name := range failed {
+		leaf := true
+		for other := range failed {
+			if strings.HasPrefix(other, name+"/") {
+				leaf = false
+				break
+			}
+		}
+		if leaf {
+			leaves = append(leaves, name)
+		}
+	}
+	sort.Strings(leaves)
+	return leaves
+}
+
+// packageFailure returns the package-level output of a run that failed
+// without any failing test, e.g. a build error; "" otherwise.
+func packageFailure(events []testEvent) string {
+	var out strings.Builder
+	failed := false
+	for _, ev := range events {
+		if ev.Test != "" {
+			continue
+		}
+		switch ev.Action {
+		case "output":
+			out.WriteString(ev.Output)
+		case "fail":
+			failed = true
+		}
+	}
+	if !failed {
+		return ""
+	}
+	return strings.TrimSpace(out.String())
+}
+
+// pickFailingTest runs the tests in pkg (from dir) and returns the single
+// failing one. With several failures it lists them so the caller can choose
+// with -run. Messages name target, the package as the caller gave it to
+// start, so the suggested commands work from the caller's directory.
+func pickFailingTest(dir, pkg, target string) (string, error) {
+	fmt.Printf("running go test -json %s to find failing tests...\n", target)
+	events, err := runTestJSON(dir, pkg)
+	if err != nil {
+		return "", err
+	}
+	failing := failingTests(events)
+	switch len(failing) {
+	case 0:
+		if out := packageFailure(events); out != "" {
+			return "", fmt.Errorf("%s fails without a failing test (build error?):\n%s", target, out)
+		}
+		return "", fmt.Errorf("no failing tests in %s", target)
+	case 1:
+		fmt.Println("failing test:", failing[0])
+		return failing[0], nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d failing tests in %s; pick one with -run:\n", len(failing), target)
+	for _, name := range failing {
+		fmt.Fprintf(&b, "  delve-helper start -test %s -run %s\n", shellQuote(target), shellQuote(name))
+	}
+	return "", fmt.Errorf("%s", strings.TrimRight(b.String(), "\n"))
+}
+
+// breakAtTest sets a breakpoint at the entry of the test function that runs
+// name; subtests stop in their parent, from where t.Run can be stepped into.
+func breakAtTest(addr, name string) error {
+	client, err := newClientAt(addr)
+	if err != nil {
+		return err
+	}
+	defer client.Disconnect(false)
+	state, err := client.GetStateNonBlocking()
+	if err != nil {
+		return err
+	}
+	return cmdBreak(client, state, []string{topLevelTest(name)})
+}
@@ -0,0 +1,238 @@
+// Session transcript: with DLV_TRANSCRIPT set, every command run through Run
+// is appended to .dlv/transcript.jsonl with its output and the stop state
+// afterwards, so a reviewer can replay exactly what the agent did.
+package delvehelper
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const transcriptFile = "transcript.jsonl"
+
+type transcriptEntry struct {
+	Time       string   `json:"time"`
+	Session    string   `json:"session,omitempty"`
+	Cmd        string   `json:"cmd"`
+	Args       []string `json:"args,omitempty"`
+	State      string   `json:"state,omitempty"`
+	Output     string   `json:"output"`
+	Err        string   `json:"error,omitempty"`
+	DurationMs int64    `json:"duration_ms"`
+}
+
+// transcriptPath returns where to record commands: "" when DLV_TRANSCRIPT is
+// unset, .dlv/transcript.jsonl for 1/true, otherwise the value as a path.
+func transcriptPath() string {
+	val := strings.TrimSpace(os.Getenv("DLV_TRANSCRIPT"))
+	switch {
+	case val == "" || val == "0":
+		return ""
+	case val == "1" || strings.EqualFold(val, "true"):
+		val = filepath.Join(getDlvDir(), transcriptFile)
+	}
+	// Absolute, because start may chdir into the target module.
+	if abs, err := filepath.Abs(val); err == nil {
+		val = abs
+	}
+	return val
+}
+
+// teeStdout runs fn with os.Stdout and the package stdout redirected into a
+// buffer, copying the output to echo as well when echo is non-nil.
+func teeStdout(echo io.Writer, fn func() error) (string, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return "", fn()
+	}
+	origOS, origStdout := os.Stdout, stdout
+	os.Stdout, stdout = w, w
+	var buf bytes.Buffer
+	dst := io.Writer(&buf)
+	if echo != nil {
+		dst = io.MultiWriter(echo, &buf)
+	}
+	copied := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(dst, r)
+		close(copied)
+	}()
+
+	runErr := fn()
+
+	os.Stdout, stdout = origOS, origStdout
+	w.Close()
+	<-copied
+	r.Close()
+	return buf.String(), runErr
+}
+
+// recordTranscript runs cmd with stdout teed into a buffer and appends the
+// result to the transcript at path. The state comes from lastState, which the
+// RPC logger updates as the command talks to Delve (or the daemon reports
+// back). Recording failures never fail the command.
+func recordTranscript(path, cmd string, args []string) error {
+	began := time.Now()
+	lastState = ""
+	output, runErr := teeStdout(os.Stdout, func() error { return dispatch(cmd, args) })
+	elapsed := time.Since(began)
+
+	e := transcriptEntry{
+		Time:       began.Format(time.RFC3339),
+		Session:    os.Getenv("DLV_SESSION"),
+		Cmd:        cmd,
+		Args:       args,
+		State:      lastState,
+		Output:     output,
+		DurationMs: elapsed.Milliseconds(),
+	}
+	if runErr != nil {
+		e.Err = runErr.Error()
+	}
+	if b, err := json.Marshal(e); err == nil {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+			_ = appendToFile(path, string(b)+"\n")
+		}
+	}
+	return runErr
+}
+
+func readTranscript(path string) ([]transcriptEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []transcriptEntry
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for sc.Scan() {
+		var e transcriptEntry
+		if json.Unmarshal(sc.Bytes(), &e) == nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries, sc.Err()
+}
+
+// commandLine renders an entry as the shell command that produced it.
+func (e transcriptEntry) commandLine() string {
+	parts := []string{"delve-helper"}
+	if e.Session != "" {
+		parts = append(parts, "-session", e.Session)
+	}
+	parts = append(parts, e.Cmd)
+	for _, a := range e.Args {
+		parts = append(parts, shellQuote(a))
+	}
+	return strings.Join(parts, " ")
+}
+
+// shellQuote single-quotes s when it contains anything beyond a safe set.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=,+@%") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// printScript writes entries as a script for cmdScript. Failed commands are
+// kept as comments, and each print gets an assert-output on its first line
+// so replaying the script checks the value it showed when recorded.
+func printScript(entries []transcriptEntry) {
+	if len(entries) > 0 {
+		fmt.Printf("# recorded %s\n", entries[0].Time)
+	}
+	for _, e := range entries {
+		if e.Cmd == "transcript" || e.Cmd == "script" {
+			continue
+		}
+		var parts []string
+		if e.Session != "" {
+			parts = append(parts, "-session", e.Session)
+		}
+		parts = append(parts, e.Cmd)
+		line := strings.Join(append(parts, quoteArgs(e.Args)...), " ")
+		if e.Err != "" {
+			msg, _, _ := strings.Cut(e.Err, "\n")
+			fmt.Printf("# failed: %s\n# %s\n", msg, line)
+			continue
+		}
+		fmt.Println(line)
+		if e.Cmd == "print" || e.Cmd == "p" {
+			out, _, _ := strings.Cut(e.Output, "\n")
+			if out = strings.TrimSpace(out); out != "" {
+				fmt.Printf("assert-output %s\n", shellQuote(out))
+			}
+		}
+	}
+}
+
+// cmdTranscript prints the recorded transcript as text, Markdown or a script.
+func cmdTranscript(args []string) error {
+	fs := flag.NewFlagSet("transcript", flag.ContinueOnError)
+	md := fs.Bool("md", false, "print as a Markdown section")
+	toScript := fs.Bool("to-script", false, "print as a file for delve-helper script")
+	file := fs.String("file", "", "transcript to read (default: $DLV_TRANSCRIPT or .dlv/"+transcriptFile+")")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	path := *file
+	if path == "" {
+		path = transcriptPath()
+	}
+	if path == "" {
+		path = filepath.Join(getDlvDir(), transcriptFile)
+	}
+	entries, err := readTranscript(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no transcript at %s (set DLV_TRANSCRIPT=1 to record commands)", path)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *toScript {
+		printScript(entries)
+		return nil
+	}
+	if *md {
+		fmt.Println("## Session Transcript")
+		fmt.Println()
+	}
+	for i, e := range entries {
+		status := e.State
+		if e.Err != "" {
+			status = "error: " + e.Err
+			if e.State != "" {
+				status += "; " + e.State
+			}
+		}
+		if !*md {
+			fmt.Printf("[%s] $ %s\n", e.Time, e.commandLine())
+			fmt.Print(e.Output)
+			if status != "" {
+				fmt.Printf("  -> %s\n", status)
+			}
+			continue
+		}
+		fmt.Printf("**%d.** %s", i+1, mdCodeSpan(e.commandLine()))
+		if status != "" {
+			fmt.Printf(" — %s", status)
+		}
+		fmt.Println()
+		fmt.Println()
+		if out := strings.TrimRight(e.Output, "\n"); out != "" {
+			fmt.Print(mdCodeBlock("text", out) + "\n")
+		}
+	}
+	return nil
+}
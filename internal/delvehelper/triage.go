@@ -0,0 +1,262 @@
+// Failing-test triage: run go test -json, extract where each test failed and
+// any panic stack, and seed the report with a Failing Tests section.
+package delvehelper
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const reportFailFile = "05_failing_tests.md"
+
+// testLogRE matches the file:line prefix testing adds to t.Errorf/t.Fatalf/t.Log.
+var testLogRE = regexp.MustCompile(`^\s+([\w./\\-]+\.go):(\d+): (.*)$`)
+
+// logCallRE matches a t.Log/t.Logf call in a source line.
+var logCallRE = regexp.MustCompile(`\.Logf?\(`)
+
+type testFailure struct {
+	Test     string
+	Messages []failureSite // t.Errorf / t.Fatalf (and t.Log) call sites, in order
+	Panic    string
+	Frames   []stackFrame // user-code frames of the panicking goroutine
+	Output   string
+}
+
+type failureSite struct {
+	File string // as printed, relative to the package dir
+	Line int
+	Msg  string
+	Log  bool // a t.Log/t.Logf line, not an error
+}
+
+// packageDir resolves the directory of pkg as seen from dir.
+func packageDir(dir, pkg string) string {
+	cmd := exec.Command("go", "list", "-f", "{{.Dir}}", pkg)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		abs, _ := filepath.Abs(filepath.Join(dir, pkg))
+		return abs
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// collectFailures groups the output of each failing test and parses it.
+func collectFailures(events []testEvent) []testFailure {
+	output := map[string]*strings.Builder{}
+	for _, ev := range events {
+		if ev.Action == "output" && ev.Test != "" {
+			if output[ev.Test] == nil {
+				output[ev.Test] = &strings.Builder{}
+			}
+			output[ev.Test].WriteString(ev.Output)
+		}
+	}
+	var failures []testFailure
+	for _, name := range failingTests(events) {
+		f := testFailure{Test: name}
+		if b := output[name]; b != nil {
+			f.Output = b.String()
+		}
+		for _, line := range strings.Split(f.Output, "\n") {
+			if m := testLogRE.FindStringSubmatch(line); m != nil {
+				n, _ := strconv.Atoi(m[2])
+				f.Messages = append(f.Messages, failureSite{File: m[1], Line: n, Msg: m[3]})
+			}
+		}
+		tb := parseTraceback(f.Output)
+		f.Panic = tb.Panic
+		if tb.Panic != "" && len(tb.Goroutines) > 0 {
+			for _, fr := range tb.Goroutines[0].Frames {
+				if !isRuntimeFrame(fr) {
+					f.Frames = append(f.Frames, fr)
+				}
+			}
+		}
+		failures = append(failures, f)
+	}
+	return failures
+}
+
+// markLogs flags the sites whose source line calls t.Log or t.Logf: go test
+// prints them with the same file:line prefix as errors, but they only add
+// context. Sites whose source cannot be read are kept as errors.
+func markLogs(failures []testFailure, pkgDir string) {
+	files := map[string][]string{}
+	for i := range failures {
+		for j := range failures[i].Messages {
+			m := &failures[i].Messages[j]
+			path := m.File
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(pkgDir, path)
+			}
+			lines, ok := files[path]
+			if !ok {
+				b, _ := os.ReadFile(path)
+				lines = strings.Split(string(b), "\n")
+				files[path] = lines
+			}
+			m.Log = m.Line >= 1 && m.Line <= len(lines) && logCallRE.MatchString(lines[m.Line-1])
+		}
+	}
+}
+
+// suggestedBreakpoints returns locspecs worth stopping at for f: the
+// innermost user frame of a panic, then each assertion site.
+func suggestedBreakpoints(f testFailure, pkgDir string) []string {
+	var locs []string
+	seen := map[string]bool{}
+	add := func(loc string) {
+		if !seen[loc] {
+			seen[loc] = true
+			locs = append(locs, loc)
+		}
+	}
+	if len(f.Frames) > 0 {
+		fr := f.Frames[0]
+		add(fmt.Sprintf("%s:%d", filepath.Base(fr.File), fr.Line))
+	}
+	for _, m := range f.Messages {
+		if m.Log {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(pkgDir, m.File)); err == nil || filepath.IsAbs(m.File) {
+			add(fmt.Sprintf("%s:%d", filepath.Base(m.File), m.Line))
+		}
+	}
+	add(topLevelTest(f.Test))
+	return locs
+}
+
+// fmtFailingTests renders the Failing Tests report section.
+func fmtFailingTests(pkg string, failures []testFailure, pkgDir string) string {
+	var sb strings.Builder
+	sb.WriteString("## Failing Tests\n\n")
+	fmt.Fprintf(&sb, "%s — %d failing test(s).\n\n", mdCodeSpan("go test -json -count=1 "+pkg), len(failures))
+	sb.WriteString("| Test | Location | Failure |\n| ---- | -------- | ------- |\n")
+	for _, f := range failures {
+		switch {
+		case f.Panic != "":
+			loc := "-"
+			if len(f.Frames) > 0 {
+				loc = mdCodeSpan(fmt.Sprintf("%s:%d", filepath.Base(f.Frames[0].File), f.Frames[0].Line))
+			}
+			first, _, _ := strings.Cut(f.Panic, "\n")
+			fmt.Fprintf(&sb, "| %s | %s | %s |\n", mdCell(f.Test), mdCell(loc), mdCell(first))
+		case len(f.Messages) == 0:
+			fmt.Fprintf(&sb, "| %s | - | (no message) |\n", mdCell(f.Test))
+		}
+		for _, m := range f.Messages {
+			msg := mdCell(m.Msg)
+			if m.Log {
+				msg = "log: " + msg
+			}
+			fmt.Fprintf(&sb, "| %s | %s | %s |\n", mdCell(f.Test), mdCell(mdCodeSpan(fmt.Sprintf("%s:%d", m.File, m.Line))), msg)
+		}
+	}
+	for _, f := range failures {
+		if len(f.Frames) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "\nPanic stack (%s, user frames):\n\n", f.Test)
+		for _, fr := range f.Frames {
+			fmt.Fprintf(&sb, "- %s at %s\n", mdCodeSpan(fr.Func), mdCodeSpan(fmt.Sprintf("%s:%d", fr.File, fr.Line)))
+		}
+	}
+	sb.WriteString("\nSuggested breakpoints:\n\n")
+	for _, f := range failures {
+		for _, loc := range suggestedBreakpoints(f, pkgDir) {
+			fmt.Fprintf(&sb, "- %s (%s)\n", mdCodeSpan(loc), mdLine(f.Test))
+		}
+	}
+	var out strings.Builder
+	for _, f := range failures {
+		out.WriteString(f.Output)
+		if !strings.HasSuffix(f.Output, "\n") {
+			out.WriteString("\n")
+		}
+	}
+	sb.WriteString("\n" + mdCodeBlock("text", strings.TrimSuffix(out.String(), "\n")))
+	return sb.String()
+}
+
+// cmdTriage runs the tests of pkg, summarizes the failures on stdout and,
+// when an artifact dir is given (-dbg or DBG_DIR), writes 05_failing_tests.md.
+func cmdTriage(args []string) error {
+	fs := flag.NewFlagSet("triage", flag.ContinueOnError)
+	dbg := fs.String("dbg", os.Getenv("DBG_DIR"), "artifact dir to write "+reportFailFile+" into (default: $DBG_DIR)")
+	run := fs.String("run", "", "only run tests matching this regexp (go test -run)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return fmt.Errorf("usage: triage [-dbg DIR] [-run REGEXP] [pkg]")
+	}
+	pkg := "."
+	if fs.NArg() == 1 {
+		pkg = fs.Arg(0)
+	}
+	// Like start: a directory that is its own module is tested from inside it.
+	dir, goPkg := "", pkg
+	if _, err := os.Stat(filepath.Join(pkg, "go.mod")); err == nil && pkg != "." {
+		dir, goPkg = pkg, "."
+	}
+	var extra []string
+	if *run != "" {
+		extra = append(extra, "-run", *run)
+	}
+	events, err := runTestJSON(dir, goPkg, extra...)
+	if err != nil {
+		return err
+	}
+	failures := collectFailures(events)
+	if len(failures) == 0 {
+		if out := packageFailure(events); out != "" {
+			return fmt.Errorf("%s fails without a failing test (build error?):\n%s", pkg, out)
+		}
+		fmt.Printf("no failing tests in %s\n", pkg)
+		return nil
+	}
+
+	pkgDir := packageDir(dir, goPkg)
+	markLogs(failures, pkgDir)
+	for _, f := range failures {
+		fmt.Printf("FAIL %s\n", f.Test)
+		if f.Panic != "" {
+			fmt.Printf("  %s\n", strings.ReplaceAll(f.Panic, "\n", "\n  "))
+			for _, fr := range f.Frames {
+				fmt.Printf("  at %s %s:%d\n", fr.Func, fr.File, fr.Line)
+			}
+		}
+		for _, m := range f.Messages {
+			kind := ""
+			if m.Log {
+				kind = "(log) "
+			}
+			fmt.Printf("  %s:%d: %s%s\n", m.File, m.Line, kind, m.Msg)
+		}
+		fmt.Printf("  debug: delve-helper start -test %s -run '%s'\n", pkg, f.Test)
+		for _, loc := range suggestedBreakpoints(f, pkgDir) {
+			fmt.Printf("  break: delve-helper break %s\n", loc)
+		}
+	}
+	if *dbg == "" {
+		return nil
+	}
+	if err := os.MkdirAll(*dbg, 0755); err != nil {
+		return fmt.Errorf("mkdir %s: %w", *dbg, err)
+	}
+	path := rfile(*dbg, reportFailFile)
+	if err := os.WriteFile(path, []byte(fmtFailingTests(pkg, failures, pkgDir)), 0644); err != nil {
+		return fmt.Errorf("write %s: %w", reportFailFile, err)
+	}
+	fmt.Println("wrote", path)
+	return nil
+}